@@ -9,6 +9,7 @@ import (
 	"github.com/nzai/go-utility/path"
 	yaml "gopkg.in/yaml.v2"
 
+	"github.com/nzai/stockrecorder/source"
 	"github.com/nzai/stockrecorder/store"
 )
 
@@ -19,6 +20,7 @@ var (
 
 // Config 配置
 type Config struct {
+	Yahoo  source.YahooFinanceConfig `yaml:"yahoo"`
 	Aliyun struct {
 		OSS store.AliyunOSSConfig `yaml:"oss"`
 	} `yaml:"aliyun"`
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestConfigYahoo(t *testing.T) {
+
+	buffer := []byte(`
+yahoo:
+    parallel: 16
+    retry: 3
+    retryinterval: "15s"
+    timeout: "30s"
+    marketparallel:
+        China: 4
+    volumemultipliers:
+        china: 100
+`)
+
+	config := new(Config)
+	if err := yaml.Unmarshal(buffer, config); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	yahoo := config.Yahoo
+	if yahoo.ParallelMax != 16 || yahoo.RetryCount != 3 || yahoo.RetryInterval != 15*time.Second || yahoo.Timeout != 30*time.Second {
+		t.Errorf("yahoo = %+v, want parallel 16, retry 3, retryinterval 15s, timeout 30s", yahoo)
+	}
+
+	if yahoo.MarketParallelMax["China"] != 4 || yahoo.VolumeMultipliers["china"] != 100 {
+		t.Errorf("per-market settings = %v/%v, want China 4 and china 100", yahoo.MarketParallelMax, yahoo.VolumeMultipliers)
+	}
+}
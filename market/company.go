@@ -8,8 +8,9 @@ import (
 
 // Company 公司
 type Company struct {
-	Name string // 名称
-	Code string // 代码
+	Name     string // 名称
+	Code     string // 代码
+	Priority int    // 抓取优先级，越大越先抓取，不参与序列化
 }
 
 // Marshal 序列化
@@ -30,9 +31,9 @@ func (c Company) Marshal() []byte {
 // Unmarshal 反序列化
 func (c *Company) Unmarshal(buffer []byte) int {
 
-	c.Code = strings.Trim(string(buffer[:16]), string(0x0))
+	c.Code = strings.Trim(string(buffer[:16]), "\x00")
 	nameLen := int(binary.BigEndian.Uint16(buffer[16:18]))
-	c.Name = strings.Trim(string(buffer[18:18+nameLen]), string(0x0))
+	c.Name = strings.Trim(string(buffer[18:18+nameLen]), "\x00")
 
 	return 19 + nameLen
 }
@@ -63,3 +64,16 @@ func (l CompanyList) Swap(i, j int) {
 func (l CompanyList) Less(i, j int) bool {
 	return l[i].Code < l[j].Code
 }
+
+// CompanyPriorityList 按抓取优先级从高到低排列的公司列表
+type CompanyPriorityList []Company
+
+func (l CompanyPriorityList) Len() int {
+	return len(l)
+}
+func (l CompanyPriorityList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+func (l CompanyPriorityList) Less(i, j int) bool {
+	return l[i].Priority > l[j].Priority
+}
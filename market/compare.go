@@ -0,0 +1,81 @@
+package market
+
+// SeriesDiff 两个报价序列的差异
+type SeriesDiff struct {
+	CountA        int     // A的报价数
+	CountB        int     // B的报价数
+	Matched       int     // 时间相同的报价数
+	OnlyA         int     // 只在A中出现的报价数
+	OnlyB         int     // 只在B中出现的报价数
+	MaxPriceDelta uint32  // 时间相同的报价中开盘、收盘、最高、最低价的最大差值
+	AvgPriceDelta float64 // 时间相同的报价中收盘价的平均差值
+	VolumeDelta   int64   // 时间相同的报价中成交量差值之和(A-B)
+}
+
+// SourceDiff 两个数据源同一天报价的差异
+type SourceDiff struct {
+	Pre     SeriesDiff
+	Regular SeriesDiff
+	Post    SeriesDiff
+}
+
+// CompareQuotes 比较两个数据源同一公司同一天的报价
+func CompareQuotes(a, b CompanyDailyQuote) SourceDiff {
+	return SourceDiff{
+		Pre:     compareSeries(a.Pre, b.Pre),
+		Regular: compareSeries(a.Regular, b.Regular),
+		Post:    compareSeries(a.Post, b.Post),
+	}
+}
+
+// compareSeries 按时间对齐比较两个报价序列
+func compareSeries(a, b QuoteSeries) SeriesDiff {
+
+	diff := SeriesDiff{CountA: int(a.Count), CountB: int(b.Count)}
+
+	indexes := make(map[uint32]int, b.Count)
+	for index, ts := range b.Timestamp[:b.Count] {
+		indexes[ts] = index
+	}
+
+	var closeDelta uint64
+	for index, ts := range a.Timestamp[:a.Count] {
+
+		bi, found := indexes[ts]
+		if !found {
+			diff.OnlyA++
+			continue
+		}
+		diff.Matched++
+
+		for _, delta := range []uint32{
+			absDelta(a.Open[index], b.Open[bi]),
+			absDelta(a.Close[index], b.Close[bi]),
+			absDelta(a.Max[index], b.Max[bi]),
+			absDelta(a.Min[index], b.Min[bi]),
+		} {
+			if delta > diff.MaxPriceDelta {
+				diff.MaxPriceDelta = delta
+			}
+		}
+
+		closeDelta += uint64(absDelta(a.Close[index], b.Close[bi]))
+		diff.VolumeDelta += int64(a.Volume[index]) - int64(b.Volume[bi])
+	}
+
+	diff.OnlyB = diff.CountB - diff.Matched
+	if diff.Matched > 0 {
+		diff.AvgPriceDelta = float64(closeDelta) / float64(diff.Matched)
+	}
+
+	return diff
+}
+
+// absDelta 差值的绝对值
+func absDelta(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
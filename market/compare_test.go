@@ -0,0 +1,51 @@
+package market
+
+import (
+	"testing"
+)
+
+func TestCompareQuotes(t *testing.T) {
+
+	a := CompanyDailyQuote{
+		Regular: barSeries(
+			Bar{60, 100, 101, 102, 99, 1000},
+			Bar{120, 101, 103, 104, 100, 2000},
+			Bar{180, 103, 102, 103, 101, 1500},
+		),
+		Post: barSeries(Bar{240, 102, 102, 102, 102, 10}),
+	}
+
+	// B缺少120，多出240，其余报价略有偏差
+	b := CompanyDailyQuote{
+		Regular: barSeries(
+			Bar{60, 100, 102, 102, 98, 900},
+			Bar{180, 104, 102, 103, 101, 1450},
+			Bar{240, 102, 102, 102, 102, 50},
+		),
+		Post: barSeries(Bar{240, 102, 102, 102, 102, 10}),
+	}
+
+	diff := CompareQuotes(a, b)
+
+	expected := SeriesDiff{
+		CountA:        3,
+		CountB:        3,
+		Matched:       2,
+		OnlyA:         1,
+		OnlyB:         1,
+		MaxPriceDelta: 1,
+		AvgPriceDelta: 0.5,
+		VolumeDelta:   150,
+	}
+	if diff.Regular != expected {
+		t.Errorf("Regular diff = %+v, want %+v", diff.Regular, expected)
+	}
+
+	if diff.Post != (SeriesDiff{CountA: 1, CountB: 1, Matched: 1}) {
+		t.Errorf("identical Post diff = %+v, want one matched bar with no deltas", diff.Post)
+	}
+
+	if diff.Pre != (SeriesDiff{}) {
+		t.Errorf("empty Pre diff = %+v, want zero", diff.Pre)
+	}
+}
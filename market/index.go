@@ -0,0 +1,77 @@
+package market
+
+import (
+	"sort"
+	"strings"
+)
+
+// ConstituentsProvider 指数成分股提供者
+type ConstituentsProvider interface {
+	// 获取指数的成分股
+	Constituents(index Company) ([]Company, error)
+}
+
+// ExpandConstituents 获取指数及其成分股，指数排在第一个
+func ExpandConstituents(provider ConstituentsProvider, index Company) ([]Company, error) {
+
+	constituents, err := provider.Constituents(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]Company{index}, constituents...), nil
+}
+
+// IndexMarket 在市场的上市公司之外同时记录指数及其成分股
+type IndexMarket struct {
+	Market
+	Provider ConstituentsProvider // 成分股提供者
+	Indexes  []Company            // 指数
+}
+
+// Companies 上市公司、指数及其成分股
+func (m IndexMarket) Companies() ([]Company, error) {
+
+	companies, err := m.Market.Companies()
+	if err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]bool, len(companies))
+	for _, company := range companies {
+		dict[company.Code] = true
+	}
+
+	for _, index := range m.Indexes {
+
+		expanded, err := ExpandConstituents(m.Provider, index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, company := range expanded {
+			//	去重
+			if _, found := dict[company.Code]; found {
+				continue
+			}
+			dict[company.Code] = true
+
+			companies = append(companies, company)
+		}
+	}
+
+	//	按Code排序
+	sort.Sort(CompanyList(companies))
+
+	return companies, nil
+}
+
+// YahooQueryCode 指数代码(以^开头，如^HSI)原样使用，其余按所在市场转换
+func (m IndexMarket) YahooQueryCode(company Company) string {
+
+	if strings.HasPrefix(company.Code, "^") {
+		return company.Code
+	}
+
+	return m.Market.YahooQueryCode(company)
+}
@@ -0,0 +1,73 @@
+package market
+
+import (
+	"testing"
+)
+
+// staticProvider 固定的成分股
+type staticProvider map[string][]Company
+
+// Constituents 获取指数的成分股
+func (p staticProvider) Constituents(index Company) ([]Company, error) {
+	return p[index.Code], nil
+}
+
+func TestIndexMarketYahooQueryCode(t *testing.T) {
+
+	tests := []struct {
+		market   Market
+		code     string
+		expected string
+	}{
+		{HongKong{}, "^HSI", "^HSI"},
+		{HongKong{}, "00700", "0700.HK"},
+		{China{}, "^SSEC", "^SSEC"},
+		{China{}, "600000", "600000.SS"},
+		{America{}, "^GSPC", "^GSPC"},
+		{America{}, "AAPL", "AAPL"},
+	}
+
+	for _, test := range tests {
+		m := IndexMarket{Market: test.market}
+		if code := m.YahooQueryCode(Company{Code: test.code}); code != test.expected {
+			t.Errorf("%s YahooQueryCode(%s) = %s, want %s", test.market.Name(), test.code, code, test.expected)
+		}
+	}
+}
+
+func TestIndexMarketCompanies(t *testing.T) {
+
+	m := IndexMarket{
+		Market:   staticMarket{America{}, []Company{{Code: "MSFT"}, {Code: "AAPL"}}},
+		Provider: staticProvider{"^DJI": {{Code: "AAPL"}, {Code: "KO"}}},
+		Indexes:  []Company{{Code: "^DJI"}},
+	}
+
+	companies, err := m.Companies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 去重并按代码排序
+	expected := []string{"AAPL", "KO", "MSFT", "^DJI"}
+	if len(companies) != len(expected) {
+		t.Fatalf("Companies = %v, want %v", companies, expected)
+	}
+
+	for index, code := range expected {
+		if companies[index].Code != code {
+			t.Errorf("Companies[%d] = %s, want %s", index, companies[index].Code, code)
+		}
+	}
+}
+
+// staticMarket 上市公司固定的市场
+type staticMarket struct {
+	America
+	companies []Company
+}
+
+// Companies 上市公司
+func (m staticMarket) Companies() ([]Company, error) {
+	return m.companies, nil
+}
@@ -0,0 +1,159 @@
+package market
+
+import (
+	"math"
+)
+
+// Outliers 按收盘价涨跌幅的滚动z-score查找异常报价，返回异常报价的序号，不修改数据
+// 每个报价的涨跌幅与之前window个涨跌幅的均值相差超过threshold个标准差时视为异常
+func (s QuoteSeries) Outliers(window int, threshold float64) []int {
+
+	if window < 2 || int(s.Count) <= window+1 {
+		return nil
+	}
+
+	// returns[i]为第i+1个报价相对第i个报价的涨跌幅
+	returns := make([]float64, s.Count-1)
+	for index := 1; index < int(s.Count); index++ {
+		if s.Close[index-1] > 0 {
+			returns[index-1] = float64(s.Close[index])/float64(s.Close[index-1]) - 1
+		}
+	}
+
+	var outliers []int
+	for index := window; index < len(returns); index++ {
+
+		mean, stddev := meanStddev(returns[index-window : index])
+		if stddev == 0 {
+			continue
+		}
+
+		if math.Abs(returns[index]-mean)/stddev > threshold {
+			outliers = append(outliers, index+1)
+		}
+	}
+
+	return outliers
+}
+
+// meanStddev 均值和标准差
+func meanStddev(values []float64) (float64, float64) {
+
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// Turnover 成交额，按典型价格(最高+最低+收盘)/3乘以成交量估算，单位为交易货币
+func (s QuoteSeries) Turnover() float64 {
+
+	var turnover float64
+	for index := 0; index < int(s.Count); index++ {
+		typical := (float64(s.Max[index]) + float64(s.Min[index]) + float64(s.Close[index])) / 3 / 100
+		turnover += typical * float64(s.Volume[index])
+	}
+
+	return turnover
+}
+
+// Turnover 当天的成交额及各交易时段的成交额
+func (q CompanyDailyQuote) Turnover() (float64, map[string]float64) {
+
+	bySession := map[string]float64{
+		"pre":     q.Pre.Turnover(),
+		"regular": q.Regular.Turnover(),
+		"post":    q.Post.Turnover(),
+	}
+
+	return bySession["pre"] + bySession["regular"] + bySession["post"], bySession
+}
+
+// SMA 收盘价的简单移动平均，单位为交易货币，前window-1个报价数据不足为NaN
+func (s QuoteSeries) SMA(window int) []float64 {
+
+	averages := make([]float64, s.Count)
+	if window < 1 {
+		for index := range averages {
+			averages[index] = math.NaN()
+		}
+		return averages
+	}
+
+	var sum float64
+	for index := 0; index < int(s.Count); index++ {
+
+		sum += float64(s.Close[index]) / 100
+		if index >= window {
+			sum -= float64(s.Close[index-window]) / 100
+		}
+
+		if index < window-1 {
+			averages[index] = math.NaN()
+			continue
+		}
+
+		averages[index] = sum / float64(window)
+	}
+
+	return averages
+}
+
+// EMA 收盘价的指数移动平均，平滑系数为2/(window+1)，以前window个报价的简单平均为初始值，之前为NaN
+func (s QuoteSeries) EMA(window int) []float64 {
+
+	averages := s.SMA(window)
+	if window < 1 || int(s.Count) < window {
+		return averages
+	}
+
+	alpha := 2 / float64(window+1)
+	for index := window; index < int(s.Count); index++ {
+		averages[index] = alpha*float64(s.Close[index])/100 + (1-alpha)*averages[index-1]
+	}
+
+	return averages
+}
+
+const (
+	// tradingDaysPerYear 每年的交易日数，用于年化
+	tradingDaysPerYear = 252
+)
+
+// RealizedVolatility 收盘价分钟对数收益率的标准差，跳过没有成交的报价，收益率少于2个时为0
+// annualize为true时按当天的收益率个数换算为日波动率，再乘以每年交易日数的平方根
+func (s QuoteSeries) RealizedVolatility(annualize bool) float64 {
+
+	var returns []float64
+	var previous uint32
+	for index := 0; index < int(s.Count); index++ {
+
+		if s.Volume[index] == 0 || s.Close[index] == 0 {
+			continue
+		}
+
+		if previous > 0 {
+			returns = append(returns, math.Log(float64(s.Close[index])/float64(previous)))
+		}
+		previous = s.Close[index]
+	}
+
+	if len(returns) < 2 {
+		return 0
+	}
+
+	_, stddev := meanStddev(returns)
+	if annualize {
+		return stddev * math.Sqrt(float64(len(returns))*tradingDaysPerYear)
+	}
+
+	return stddev
+}
@@ -0,0 +1,170 @@
+package market
+
+import (
+	"math"
+	"testing"
+)
+
+// closeSeries 只有收盘价的报价序列，收盘价单位为分
+func closeSeries(closes ...uint32) QuoteSeries {
+	return QuoteSeries{Count: uint32(len(closes)), Close: closes}
+}
+
+// floatsEqual 比较浮点数组，NaN与NaN视为相等
+func floatsEqual(a, b []float64) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if math.IsNaN(a[index]) && math.IsNaN(b[index]) {
+			continue
+		}
+
+		if math.Abs(a[index]-b[index]) > 1e-9 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestMovingAverages(t *testing.T) {
+
+	nan := math.NaN()
+	series := closeSeries(100, 200, 400, 800, 1600)
+
+	tests := []struct {
+		name   string
+		window int
+		sma    []float64
+		ema    []float64
+	}{
+		{"window1", 1, []float64{1, 2, 4, 8, 16}, []float64{1, 2, 4, 8, 16}},
+		// alpha = 2/(3+1) = 0.5，以前3个的简单平均7/3为初始值
+		{"window3", 3, []float64{nan, nan, 7.0 / 3, 14.0 / 3, 28.0 / 3}, []float64{nan, nan, 7.0 / 3, 31.0 / 6, 127.0 / 12}},
+		{"window5", 5, []float64{nan, nan, nan, nan, 31.0 / 5}, []float64{nan, nan, nan, nan, 31.0 / 5}},
+		{"longer", 6, []float64{nan, nan, nan, nan, nan}, []float64{nan, nan, nan, nan, nan}},
+		{"zero", 0, []float64{nan, nan, nan, nan, nan}, []float64{nan, nan, nan, nan, nan}},
+		{"negative", -1, []float64{nan, nan, nan, nan, nan}, []float64{nan, nan, nan, nan, nan}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			if sma := series.SMA(test.window); !floatsEqual(sma, test.sma) {
+				t.Errorf("SMA(%d) = %v, want %v", test.window, sma, test.sma)
+			}
+
+			if ema := series.EMA(test.window); !floatsEqual(ema, test.ema) {
+				t.Errorf("EMA(%d) = %v, want %v", test.window, ema, test.ema)
+			}
+		})
+	}
+}
+
+func TestMovingAveragesEmpty(t *testing.T) {
+
+	var series QuoteSeries
+	if sma := series.SMA(3); len(sma) != 0 {
+		t.Errorf("SMA on empty series = %v, want empty", sma)
+	}
+
+	if ema := series.EMA(-2); len(ema) != 0 {
+		t.Errorf("EMA on empty series = %v, want empty", ema)
+	}
+}
+
+func TestOutliers(t *testing.T) {
+
+	// 平稳的序列，第20个报价突然上涨20%后回落
+	closes := make([]uint32, 30)
+	for index := range closes {
+		closes[index] = 10000 + uint32(index%3)*5
+	}
+	closes[20] = 12000
+	series := closeSeries(closes...)
+
+	outliers := series.Outliers(10, 10)
+	if len(outliers) != 1 || outliers[0] != 20 {
+		t.Errorf("Outliers = %v, want [20]", outliers)
+	}
+
+	// 不修改数据
+	if series.Close[20] != 12000 {
+		t.Errorf("Outliers changed the spike to %d", series.Close[20])
+	}
+
+	tests := []struct {
+		name   string
+		series QuoteSeries
+		window int
+	}{
+		{"window too small", series, 1},
+		{"series too short", closeSeries(closes[15:25]...), 10},
+		// 标准差为0时不判断
+		{"flat series", closeSeries(100, 100, 100, 100, 100, 100, 100), 3},
+	}
+
+	for _, test := range tests {
+		if outliers := test.series.Outliers(test.window, 10); len(outliers) != 0 {
+			t.Errorf("%s: Outliers = %v, want none", test.name, outliers)
+		}
+	}
+}
+
+func TestTurnover(t *testing.T) {
+
+	quote := CompanyDailyQuote{
+		// 典型价格(3+1+2)/3=2，成交额20
+		Pre: barSeries(Bar{60, 150, 200, 300, 100, 10}),
+		// 典型价格(12+9+9)/3=10和(6+3+3)/3=4，成交额50+100
+		Regular: barSeries(
+			Bar{120, 1000, 900, 1200, 900, 5},
+			Bar{180, 500, 300, 600, 300, 25},
+		),
+	}
+
+	total, bySession := quote.Turnover()
+	if math.Abs(total-170) > 1e-9 {
+		t.Errorf("total turnover = %v, want 170", total)
+	}
+
+	expected := map[string]float64{"pre": 20, "regular": 150, "post": 0}
+	for session, turnover := range expected {
+		if math.Abs(bySession[session]-turnover) > 1e-9 {
+			t.Errorf("%s turnover = %v, want %v", session, bySession[session], turnover)
+		}
+	}
+}
+
+func TestRealizedVolatility(t *testing.T) {
+
+	// 收盘价在1元和2元之间来回，对数收益率为±ln2，标准差为ln2
+	// 没有成交的报价价格异常，应当跳过
+	swing := QuoteSeries{
+		Count:  7,
+		Close:  []uint32{100, 500, 200, 100, 200, 900, 100},
+		Volume: []uint32{10, 0, 10, 10, 10, 0, 10},
+	}
+
+	tests := []struct {
+		name      string
+		series    QuoteSeries
+		annualize bool
+		want      float64
+	}{
+		{"swing", swing, false, math.Ln2},
+		{"swing annualized", swing, true, math.Ln2 * math.Sqrt(4*252)},
+		{"flat", QuoteSeries{Count: 3, Close: []uint32{100, 100, 100}, Volume: []uint32{1, 1, 1}}, false, 0},
+		{"one return", QuoteSeries{Count: 2, Close: []uint32{100, 200}, Volume: []uint32{1, 1}}, true, 0},
+		{"no trades", QuoteSeries{Count: 3, Close: []uint32{100, 200, 100}, Volume: []uint32{0, 0, 0}}, false, 0},
+	}
+
+	for _, test := range tests {
+		if got := test.series.RealizedVolatility(test.annualize); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: RealizedVolatility(%v) = %v, want %v", test.name, test.annualize, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// jsonSchemaVersion json格式版本，格式变化时递增
+	jsonSchemaVersion = 1
+)
+
+// jsonDailyQuote 市场每日报价的json格式，与结构体字段名无关
+type jsonDailyQuote struct {
+	Version   int                     `json:"version"`
+	Market    string                  `json:"market"`
+	UTCOffset int                     `json:"utc_offset"`
+	Date      string                  `json:"date"`
+	Quotes    []jsonCompanyDailyQuote `json:"quotes"`
+}
+
+// jsonCompanyDailyQuote 公司每日报价的json格式
+type jsonCompanyDailyQuote struct {
+	Code    string    `json:"code"`
+	Name    string    `json:"name"`
+	Pre     []jsonBar `json:"pre"`
+	Regular []jsonBar `json:"regular"`
+	Post    []jsonBar `json:"post"`
+}
+
+// jsonBar 分时报价的json格式，价格单位为分
+type jsonBar struct {
+	Time   string `json:"time"`
+	Open   uint32 `json:"open"`
+	Close  uint32 `json:"close"`
+	High   uint32 `json:"high"`
+	Low    uint32 `json:"low"`
+	Volume uint32 `json:"volume"`
+}
+
+// MarshalJSON 序列化为带版本号的json，时间使用RFC3339格式
+func (q DailyQuote) MarshalJSON() ([]byte, error) {
+
+	if q.Market == nil {
+		return nil, ErrUnknownMarket
+	}
+
+	value := jsonDailyQuote{
+		Version:   jsonSchemaVersion,
+		Market:    q.Market.Name(),
+		UTCOffset: q.UTCOffset,
+		Date:      q.Date.Format(time.RFC3339),
+		Quotes:    make([]jsonCompanyDailyQuote, 0, len(q.Quotes)),
+	}
+
+	for _, quote := range q.Quotes {
+		value.Quotes = append(value.Quotes, jsonCompanyDailyQuote{
+			Code:    quote.Code,
+			Name:    quote.Name,
+			Pre:     seriesToJSON(quote.Pre),
+			Regular: seriesToJSON(quote.Regular),
+			Post:    seriesToJSON(quote.Post),
+		})
+	}
+
+	return json.Marshal(value)
+}
+
+// UnmarshalJSON 从MarshalJSON生成的json反序列化
+func (q *DailyQuote) UnmarshalJSON(data []byte) error {
+
+	var value jsonDailyQuote
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	if value.Version != jsonSchemaVersion {
+		return fmt.Errorf("不支持的json格式版本: %d", value.Version)
+	}
+
+	_market, err := Get(value.Market)
+	if err != nil {
+		return err
+	}
+
+	date, err := time.Parse(time.RFC3339, value.Date)
+	if err != nil {
+		return err
+	}
+
+	// 与Unmarshal一致，日期使用市场所在时区
+	location, err := time.LoadLocation(_market.Timezone())
+	if err != nil {
+		location = time.Local
+	}
+
+	quotes := make([]CompanyDailyQuote, 0, len(value.Quotes))
+	for _, quote := range value.Quotes {
+
+		cdq := CompanyDailyQuote{Company: Company{Code: quote.Code, Name: quote.Name}}
+		if cdq.Pre, err = seriesFromJSON(quote.Pre); err != nil {
+			return err
+		}
+
+		if cdq.Regular, err = seriesFromJSON(quote.Regular); err != nil {
+			return err
+		}
+
+		if cdq.Post, err = seriesFromJSON(quote.Post); err != nil {
+			return err
+		}
+
+		quotes = append(quotes, cdq)
+	}
+
+	q.Market = _market
+	q.UTCOffset = value.UTCOffset
+	q.Date = date.In(location)
+	q.Quotes = quotes
+
+	return nil
+}
+
+// seriesToJSON 转换分时报价
+func seriesToJSON(series QuoteSeries) []jsonBar {
+
+	bars := make([]jsonBar, 0, series.Count)
+	for index := 0; index < int(series.Count); index++ {
+
+		bar := series.Bar(index)
+		bars = append(bars, jsonBar{
+			Time:   time.Unix(int64(bar.Timestamp), 0).UTC().Format(time.RFC3339),
+			Open:   bar.Open,
+			Close:  bar.Close,
+			High:   bar.Max,
+			Low:    bar.Min,
+			Volume: bar.Volume,
+		})
+	}
+
+	return bars
+}
+
+// seriesFromJSON 还原分时报价
+func seriesFromJSON(bars []jsonBar) (QuoteSeries, error) {
+
+	series := QuoteSeries{Count: uint32(len(bars))}
+	for _, bar := range bars {
+
+		timestamp, err := time.Parse(time.RFC3339, bar.Time)
+		if err != nil {
+			return QuoteSeries{}, err
+		}
+
+		series.Timestamp = append(series.Timestamp, uint32(timestamp.Unix()))
+		series.Open = append(series.Open, bar.Open)
+		series.Close = append(series.Close, bar.Close)
+		series.Max = append(series.Max, bar.High)
+		series.Min = append(series.Min, bar.Low)
+		series.Volume = append(series.Volume, bar.Volume)
+	}
+
+	return series, nil
+}
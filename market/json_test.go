@@ -0,0 +1,89 @@
+package market
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+
+	location, err := time.LoadLocation(America{}.Timezone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2017, 6, 1, 0, 0, 0, 0, location)
+	_, offset := date.Zone()
+
+	quote := DailyQuote{
+		Market:    America{},
+		Date:      date,
+		UTCOffset: offset,
+		Quotes: []CompanyDailyQuote{
+			{
+				Company: Company{Code: "AAPL", Name: "Apple Inc."},
+				Pre:     barSeries(Bar{1496322000, 15280, 15285, 15290, 15275, 1200}),
+				Regular: barSeries(
+					Bar{1496323800, 15317, 15291, 15320, 15280, 901234},
+					Bar{1496323860, 15290, 15256, 15295, 15250, 252100},
+				),
+				Post: barSeries(Bar{1496347200, 15320, 15310, 15325, 15305, 45000}),
+			},
+			// 没有报价的公司
+			{Company: Company{Code: "MSFT", Name: "Microsoft Corporation"}},
+		},
+	}
+
+	buffer, err := json.Marshal(quote)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	// 字段名与结构体无关，时间为RFC3339
+	for _, field := range []string{`"version":1`, `"market":"America"`, `"date":"2017-06-01T00:00:00-04:00"`, `"time":"2017-06-01T13:00:00Z"`, `"high":15290`} {
+		if !strings.Contains(string(buffer), field) {
+			t.Errorf("json %s does not contain %s", buffer, field)
+		}
+	}
+
+	var decoded DailyQuote
+	if err = json.Unmarshal(buffer, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if !decoded.Date.Equal(quote.Date) || decoded.Date.Location().String() != location.String() {
+		t.Errorf("date = %s, want %s", decoded.Date, quote.Date)
+	}
+
+	// 时区指针不同，日期单独比较
+	decoded.Date, quote.Date = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(decoded, quote) {
+		t.Errorf("round trip = %+v, want %+v", decoded, quote)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"version", `{"version": 2, "market": "America", "date": "2017-06-01T00:00:00-04:00", "quotes": []}`},
+		{"market", `{"version": 1, "market": "Mars", "date": "2017-06-01T00:00:00-04:00", "quotes": []}`},
+		{"date", `{"version": 1, "market": "America", "date": "20170601", "quotes": []}`},
+		{"bar time", `{"version": 1, "market": "America", "date": "2017-06-01T00:00:00-04:00", "quotes": [{"code": "AAPL", "regular": [{"time": "13:00"}]}]}`},
+	}
+
+	for _, test := range tests {
+		var quote DailyQuote
+		if err := json.Unmarshal([]byte(test.json), &quote); err == nil {
+			t.Errorf("Unmarshal with a bad %s should fail", test.name)
+		}
+	}
+
+	if _, err := json.Marshal(DailyQuote{}); err == nil {
+		t.Error("Marshal without a market should fail")
+	}
+}
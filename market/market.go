@@ -2,6 +2,7 @@ package market
 
 import (
 	"errors"
+	"sort"
 	"strings"
 )
 
@@ -28,10 +29,12 @@ var (
 	ErrUnknownMarket = errors.New("未知的市场")
 )
 
+// markets 支持的市场
+var markets = []Market{America{}, China{}, HongKong{}}
+
 // Get 获取市场
 func Get(name string) (Market, error) {
 
-	markets := []Market{America{}, China{}, HongKong{}}
 	for _, market := range markets {
 		if strings.ToLower(market.Name()) != strings.ToLower(name) {
 			continue
@@ -42,3 +45,31 @@ func Get(name string) (Market, error) {
 
 	return nil, ErrUnknownMarket
 }
+
+// MarketInfo 市场信息
+type MarketInfo struct {
+	Name     string   // 名称
+	Timezone string   // 时区
+	Suffixes []string // 雅虎查询代码后缀，没有后缀时为空
+}
+
+// SupportedMarkets 支持的市场及其时区和雅虎查询代码后缀
+// 交易时段每天由雅虎返回的tradingPeriods确定，不在此列出
+func SupportedMarkets() []MarketInfo {
+
+	infos := make([]MarketInfo, 0, len(markets))
+	for _, market := range markets {
+
+		info := MarketInfo{Name: market.Name(), Timezone: market.Timezone()}
+		for suffix, exchange := range yahooExchanges {
+			if exchange.market == market.Name() {
+				info.Suffixes = append(info.Suffixes, suffix)
+			}
+		}
+		sort.Strings(info.Suffixes)
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
@@ -0,0 +1,38 @@
+package market
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSupportedMarkets(t *testing.T) {
+
+	expected := map[string]MarketInfo{
+		"America":  {Name: "America", Timezone: "America/New_York"},
+		"China":    {Name: "China", Timezone: "Asia/Shanghai", Suffixes: []string{"SS", "SZ"}},
+		"HongKong": {Name: "HongKong", Timezone: "Asia/Hong_Kong", Suffixes: []string{"HK"}},
+	}
+
+	infos := SupportedMarkets()
+	if len(infos) != len(expected) {
+		t.Errorf("SupportedMarkets = %+v, want %d markets", infos, len(expected))
+	}
+
+	for _, info := range infos {
+
+		if !reflect.DeepEqual(info, expected[info.Name]) {
+			t.Errorf("%s = %+v, want %+v", info.Name, info, expected[info.Name])
+		}
+
+		// 与解析时使用的后缀表一致
+		for _, suffix := range info.Suffixes {
+			if _, timezone, ok := ExchangeFromQueryCode("1." + suffix); !ok || timezone != info.Timezone {
+				t.Errorf("suffix %s maps to %q, want %s", suffix, timezone, info.Timezone)
+			}
+		}
+
+		if _, err := Get(info.Name); err != nil {
+			t.Errorf("Get(%s): %v", info.Name, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package market
+
+// QualityWeights 数据质量评分中各项问题的权重，权重之和为1时最低分为0
+type QualityWeights struct {
+	Invalid float64 // 价格不合理(开盘或收盘价不在最高最低价之间)
+	Gap     float64 // 正常交易时段中缺失的分钟
+	Zero    float64 // 成交量为0
+	Flat    float64 // 价格没有任何变化
+}
+
+var (
+	// DefaultQualityWeights 默认的数据质量评分权重
+	DefaultQualityWeights = QualityWeights{Invalid: 0.4, Gap: 0.3, Zero: 0.2, Flat: 0.1}
+)
+
+// ScoreQuality 根据正常交易时段的报价计算0-100的数据质量评分，没有报价时为0
+func ScoreQuality(q CompanyDailyQuote, weights QualityWeights) int {
+
+	s := q.Regular
+	if s.Count == 0 {
+		return 0
+	}
+
+	var invalid, zero, flat int
+	for index := 0; index < int(s.Count); index++ {
+
+		if s.Min[index] == 0 || s.Min[index] > s.Max[index] ||
+			s.Open[index] < s.Min[index] || s.Open[index] > s.Max[index] ||
+			s.Close[index] < s.Min[index] || s.Close[index] > s.Max[index] {
+			invalid++
+		}
+
+		if s.Volume[index] == 0 {
+			zero++
+		}
+
+		if index > 0 && s.Open[index] == s.Close[index] && s.Max[index] == s.Min[index] && s.Close[index] == s.Close[index-1] {
+			flat++
+		}
+	}
+
+	// 首尾之间应有的分钟数
+	expected := int(s.Timestamp[s.Count-1]-s.Timestamp[0])/60 + 1
+	gap := expected - int(s.Count)
+	if gap < 0 {
+		gap = 0
+	}
+
+	count := float64(s.Count)
+	penalty := weights.Invalid*float64(invalid)/count +
+		weights.Gap*float64(gap)/float64(expected) +
+		weights.Zero*float64(zero)/count +
+		weights.Flat*float64(flat)/count
+
+	score := int(100 * (1 - penalty))
+	if score < 0 {
+		return 0
+	}
+
+	if score > 100 {
+		return 100
+	}
+
+	return score
+}
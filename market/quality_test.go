@@ -0,0 +1,52 @@
+package market
+
+import (
+	"testing"
+)
+
+func TestScoreQuality(t *testing.T) {
+
+	good := func(timestamp uint32, close uint32) Bar {
+		return Bar{Timestamp: timestamp, Open: close - 1, Close: close, Max: close + 1, Min: close - 2, Volume: 100}
+	}
+
+	zeroVolume := good(120, 102)
+	zeroVolume.Volume = 0
+
+	invalid := good(120, 102)
+	invalid.Open = invalid.Max + 1
+
+	flat := Bar{Open: 100, Close: 100, Max: 100, Min: 100, Volume: 100}
+	flatBar := func(timestamp uint32) Bar {
+		bar := flat
+		bar.Timestamp = timestamp
+		return bar
+	}
+
+	tests := []struct {
+		name     string
+		bars     []Bar
+		weights  QualityWeights
+		expected int
+	}{
+		{"empty", nil, DefaultQualityWeights, 0},
+		{"clean", []Bar{good(0, 100), good(60, 101), good(120, 102), good(180, 103)}, DefaultQualityWeights, 100},
+		// 0.2 * 1/4
+		{"zero volume", []Bar{good(0, 100), good(60, 101), zeroVolume, good(180, 103)}, DefaultQualityWeights, 95},
+		// 0.4 * 1/4
+		{"invalid", []Bar{good(0, 100), good(60, 101), invalid, good(180, 103)}, DefaultQualityWeights, 90},
+		// 0.3 * 1/4，首尾之间应有4个报价
+		{"gap", []Bar{good(0, 100), good(60, 101), good(180, 103)}, DefaultQualityWeights, 92},
+		// 0.1 * 3/4，第一个报价不算
+		{"flat", []Bar{flatBar(0), flatBar(60), flatBar(120), flatBar(180)}, DefaultQualityWeights, 92},
+		// 扣分超过100时为0
+		{"clamped", []Bar{invalid, invalid}, QualityWeights{Invalid: 5}, 0},
+	}
+
+	for _, test := range tests {
+		cdq := CompanyDailyQuote{Regular: barSeries(test.bars...)}
+		if score := ScoreQuality(cdq, test.weights); score != test.expected {
+			t.Errorf("%s: ScoreQuality = %d, want %d", test.name, score, test.expected)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CanonicalizeQueryCode 去掉空白并统一为大写的雅虎查询代码，如" 600000.ss"转换为"600000.SS"
+func CanonicalizeQueryCode(code string) (string, error) {
+
+	canonical := strings.ToUpper(strings.TrimSpace(code))
+	if canonical == "" {
+		return "", fmt.Errorf("查询代码为空")
+	}
+
+	for _, r := range canonical {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '-', r == '^', r == '=':
+		default:
+			return "", fmt.Errorf("查询代码%q包含无效字符%q", code, r)
+		}
+	}
+
+	// 交易所后缀最多一个，且前后都不能为空
+	parts := strings.Split(canonical, ".")
+	if len(parts) > 2 {
+		return "", fmt.Errorf("查询代码%q包含多个后缀", code)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("查询代码%q格式不正确", code)
+		}
+	}
+
+	if len(parts) == 2 && strings.Trim(parts[1], "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("查询代码%q的后缀%q格式不正确", code, parts[1])
+	}
+
+	return canonical, nil
+}
+
+// yahooExchanges 雅虎查询代码后缀对应的交易所、IANA时区和市场
+var yahooExchanges = map[string]struct {
+	exchange string
+	timezone string
+	market   string // 支持的市场名称，不支持时为空
+}{
+	"SS": {"Shanghai", "Asia/Shanghai", "China"},
+	"SZ": {"Shenzhen", "Asia/Shanghai", "China"},
+	"HK": {"HKEX", "Asia/Hong_Kong", "HongKong"},
+	"T":  {"Tokyo", "Asia/Tokyo", ""},
+	"KS": {"KRX", "Asia/Seoul", ""},
+	"KQ": {"KOSDAQ", "Asia/Seoul", ""},
+	"TW": {"Taiwan", "Asia/Taipei", ""},
+	"SI": {"SGX", "Asia/Singapore", ""},
+	"AX": {"ASX", "Australia/Sydney", ""},
+	"NS": {"NSE", "Asia/Kolkata", ""},
+	"BO": {"BSE", "Asia/Kolkata", ""},
+	"L":  {"LSE", "Europe/London", ""},
+	"DE": {"XETRA", "Europe/Berlin", ""},
+	"F":  {"Frankfurt", "Europe/Berlin", ""},
+	"PA": {"Paris", "Europe/Paris", ""},
+	"AS": {"Amsterdam", "Europe/Amsterdam", ""},
+	"MI": {"Milan", "Europe/Rome", ""},
+	"SW": {"SIX", "Europe/Zurich", ""},
+	"TO": {"Toronto", "America/Toronto", ""},
+	"V":  {"TSXV", "America/Toronto", ""},
+	"SA": {"B3", "America/Sao_Paulo", ""},
+}
+
+// ExchangeFromQueryCode 根据雅虎查询代码的后缀判断交易所和IANA时区，没有后缀或后缀未知时ok为false
+func ExchangeFromQueryCode(code string) (exchange, timezone string, ok bool) {
+
+	canonical, err := CanonicalizeQueryCode(code)
+	if err != nil {
+		return "", "", false
+	}
+
+	index := strings.LastIndex(canonical, ".")
+	if index < 0 {
+		return "", "", false
+	}
+
+	found, ok := yahooExchanges[canonical[index+1:]]
+	if !ok {
+		return "", "", false
+	}
+
+	return found.exchange, found.timezone, true
+}
@@ -0,0 +1,75 @@
+package market
+
+import (
+	"testing"
+)
+
+func TestCanonicalizeQueryCode(t *testing.T) {
+
+	tests := []struct {
+		code     string
+		expected string
+		valid    bool
+	}{
+		{"AAPL", "AAPL", true},
+		{" aapl\t", "AAPL", true},
+		{"600000.ss", "600000.SS", true},
+		{"0700.Hk ", "0700.HK", true},
+		{"brk-b", "BRK-B", true},
+		{"^gspc", "^GSPC", true},
+		{"eurusd=x", "EURUSD=X", true},
+		{"", "", false},
+		{"   ", "", false},
+		{"AA PL", "", false},
+		{"AAPL/", "", false},
+		{"600000..SS", "", false},
+		{"600000.SS.SS", "", false},
+		{".SS", "", false},
+		{"600000.", "", false},
+		{"600000.S1", "", false},
+	}
+
+	for _, test := range tests {
+
+		canonical, err := CanonicalizeQueryCode(test.code)
+		if (err == nil) != test.valid {
+			t.Errorf("CanonicalizeQueryCode(%q) error = %v, want valid %v", test.code, err, test.valid)
+			continue
+		}
+
+		if canonical != test.expected {
+			t.Errorf("CanonicalizeQueryCode(%q) = %q, want %q", test.code, canonical, test.expected)
+		}
+	}
+}
+
+func TestExchangeFromQueryCode(t *testing.T) {
+
+	tests := []struct {
+		code     string
+		exchange string
+		timezone string
+		ok       bool
+	}{
+		{"600000.SS", "Shanghai", "Asia/Shanghai", true},
+		{"000001.SZ", "Shenzhen", "Asia/Shanghai", true},
+		{"0700.HK", "HKEX", "Asia/Hong_Kong", true},
+		{"7203.T", "Tokyo", "Asia/Tokyo", true},
+		{"005930.KS", "KRX", "Asia/Seoul", true},
+		{"VOD.L", "LSE", "Europe/London", true},
+		{"SAP.DE", "XETRA", "Europe/Berlin", true},
+		{"RY.TO", "Toronto", "America/Toronto", true},
+		// 后缀大小写不敏感
+		{" bhp.ax", "ASX", "Australia/Sydney", true},
+		{"AAPL", "", "", false},
+		{"BRK.B", "", "", false},
+		{"600000..SS", "", "", false},
+	}
+
+	for _, test := range tests {
+		exchange, timezone, ok := ExchangeFromQueryCode(test.code)
+		if exchange != test.exchange || timezone != test.timezone || ok != test.ok {
+			t.Errorf("ExchangeFromQueryCode(%q) = %q, %q, %v, want %q, %q, %v", test.code, exchange, timezone, ok, test.exchange, test.timezone, test.ok)
+		}
+	}
+}
@@ -78,6 +78,30 @@ func (q DailyQuote) Equal(s DailyQuote) error {
 	return nil
 }
 
+// Find 按代码查找公司报价
+func (q DailyQuote) Find(code string) (*CompanyDailyQuote, bool) {
+
+	for index := range q.Quotes {
+		if q.Quotes[index].Code == code {
+			return &q.Quotes[index], true
+		}
+	}
+
+	return nil, false
+}
+
+// IsHoliday 是否休市，没有任何公司有正常交易时段报价时视为休市，只有盘前盘后报价的日期也算
+func (q DailyQuote) IsHoliday() bool {
+
+	for _, cdq := range q.Quotes {
+		if cdq.Regular.Count > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 // CompanyDailyQuote 公司每日报价
 type CompanyDailyQuote struct {
 	Company
@@ -134,6 +158,18 @@ func (q CompanyDailyQuote) Equal(s CompanyDailyQuote) error {
 	return nil
 }
 
+// Last 当天最后一个报价(依次查找盘后、正常和盘前时段)
+func (q CompanyDailyQuote) Last() (Bar, bool) {
+
+	for _, series := range []QuoteSeries{q.Post, q.Regular, q.Pre} {
+		if series.Count > 0 {
+			return series.Bar(int(series.Count) - 1), true
+		}
+	}
+
+	return Bar{}, false
+}
+
 // Glance 显示摘要
 func (q CompanyDailyQuote) Glance(logger *log.Logger, location *time.Location) {
 
@@ -155,6 +191,75 @@ type QuoteSeries struct {
 	Volume    []uint32
 }
 
+// Bar 单个分时报价
+type Bar struct {
+	Timestamp uint32
+	Open      uint32
+	Close     uint32
+	Max       uint32
+	Min       uint32
+	Volume    uint32
+}
+
+// Bar 第index个报价
+func (s QuoteSeries) Bar(index int) Bar {
+	return Bar{
+		Timestamp: s.Timestamp[index],
+		Open:      s.Open[index],
+		Close:     s.Close[index],
+		Max:       s.Max[index],
+		Min:       s.Min[index],
+		Volume:    s.Volume[index],
+	}
+}
+
+// Summary 汇总为一个报价：首个开盘价、最后收盘价、最高价、最低价和成交量之和，时间为首个报价的时间
+func (s QuoteSeries) Summary() (Bar, bool) {
+
+	if s.Count == 0 {
+		return Bar{}, false
+	}
+
+	summary := s.Bar(0)
+	for index := 1; index < int(s.Count); index++ {
+
+		if s.Max[index] > summary.Max {
+			summary.Max = s.Max[index]
+		}
+
+		if s.Min[index] < summary.Min {
+			summary.Min = s.Min[index]
+		}
+
+		summary.Volume += s.Volume[index]
+	}
+	summary.Close = s.Close[s.Count-1]
+
+	return summary, true
+}
+
+// ActiveBounds 第一个和最后一个有成交的报价时间，没有任何成交时ok为false
+func (s QuoteSeries) ActiveBounds() (first, last time.Time, ok bool) {
+
+	firstIndex, lastIndex := -1, -1
+	for index := 0; index < int(s.Count); index++ {
+		if s.Volume[index] == 0 {
+			continue
+		}
+
+		if firstIndex < 0 {
+			firstIndex = index
+		}
+		lastIndex = index
+	}
+
+	if firstIndex < 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return time.Unix(int64(s.Timestamp[firstIndex]), 0), time.Unix(int64(s.Timestamp[lastIndex]), 0), true
+}
+
 // Marshal 序列化
 func (s QuoteSeries) Marshal() []byte {
 	buffer := make([]byte, s.Len())
@@ -267,6 +372,72 @@ func (s QuoteSeries) Equal(q QuoteSeries) error {
 	return nil
 }
 
+// Deduplicate 合并时间相同的相邻报价，保留后出现的(最终)报价，返回删除的数量
+func (s *QuoteSeries) Deduplicate() int {
+
+	if s.Count < 2 {
+		return 0
+	}
+
+	last := 0
+	for index := 1; index < int(s.Count); index++ {
+
+		// 时间不同则保留
+		if s.Timestamp[index] != s.Timestamp[last] {
+			last++
+		}
+
+		s.Timestamp[last] = s.Timestamp[index]
+		s.Open[last] = s.Open[index]
+		s.Close[last] = s.Close[index]
+		s.Max[last] = s.Max[index]
+		s.Min[last] = s.Min[index]
+		s.Volume[last] = s.Volume[index]
+	}
+
+	count := uint32(last + 1)
+	removed := int(s.Count - count)
+
+	s.Count = count
+	s.Timestamp = s.Timestamp[:count]
+	s.Open = s.Open[:count]
+	s.Close = s.Close[:count]
+	s.Max = s.Max[:count]
+	s.Min = s.Min[:count]
+	s.Volume = s.Volume[:count]
+
+	return removed
+}
+
+// PartialLast 最后一个报价是否被交易时段结束截断
+func (s QuoteSeries) PartialLast(session Session, interval time.Duration) bool {
+
+	if s.Count == 0 {
+		return false
+	}
+
+	return session.PartialBar(time.Unix(int64(s.Timestamp[s.Count-1]), 0), interval)
+}
+
+// TrimPartialLast 删除被交易时段结束截断的最后一个报价，返回是否删除
+func (s *QuoteSeries) TrimPartialLast(session Session, interval time.Duration) bool {
+
+	if !s.PartialLast(session, interval) {
+		return false
+	}
+
+	count := s.Count - 1
+	s.Count = count
+	s.Timestamp = s.Timestamp[:count]
+	s.Open = s.Open[:count]
+	s.Close = s.Close[:count]
+	s.Max = s.Max[:count]
+	s.Min = s.Min[:count]
+	s.Volume = s.Volume[:count]
+
+	return true
+}
+
 // arrayEqual 数组是否相同
 func (s QuoteSeries) arrayEqual(a []uint32, b []uint32) error {
 	if len(a) != len(b) {
@@ -0,0 +1,239 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+// barSeries 由报价组成的序列
+func barSeries(bars ...Bar) QuoteSeries {
+
+	var series QuoteSeries
+	for _, bar := range bars {
+		series.Count++
+		series.Timestamp = append(series.Timestamp, bar.Timestamp)
+		series.Open = append(series.Open, bar.Open)
+		series.Close = append(series.Close, bar.Close)
+		series.Max = append(series.Max, bar.Max)
+		series.Min = append(series.Min, bar.Min)
+		series.Volume = append(series.Volume, bar.Volume)
+	}
+
+	return series
+}
+
+// seriesBars 序列中的所有报价
+func seriesBars(series QuoteSeries) []Bar {
+
+	bars := make([]Bar, 0, series.Count)
+	for index := 0; index < int(series.Count); index++ {
+		bars = append(bars, series.Bar(index))
+	}
+
+	return bars
+}
+
+// barsEqual 比较报价
+func barsEqual(a, b []Bar) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestDeduplicate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		bars     []Bar
+		expected []Bar
+		removed  int
+	}{
+		{"empty", nil, []Bar{}, 0},
+		{"single", []Bar{{Timestamp: 60, Close: 1}}, []Bar{{Timestamp: 60, Close: 1}}, 0},
+		{"unique", []Bar{{Timestamp: 60, Close: 1}, {Timestamp: 120, Close: 2}}, []Bar{{Timestamp: 60, Close: 1}, {Timestamp: 120, Close: 2}}, 0},
+		// 保留后出现的报价
+		{
+			"duplicates",
+			[]Bar{{Timestamp: 60, Close: 1}, {Timestamp: 60, Close: 2}, {Timestamp: 120, Close: 3}, {Timestamp: 180, Close: 4}, {Timestamp: 180, Close: 5}, {Timestamp: 180, Close: 6}},
+			[]Bar{{Timestamp: 60, Close: 2}, {Timestamp: 120, Close: 3}, {Timestamp: 180, Close: 6}},
+			3,
+		},
+		// 只合并相邻的报价
+		{
+			"not adjacent",
+			[]Bar{{Timestamp: 60, Close: 1}, {Timestamp: 120, Close: 2}, {Timestamp: 60, Close: 3}},
+			[]Bar{{Timestamp: 60, Close: 1}, {Timestamp: 120, Close: 2}, {Timestamp: 60, Close: 3}},
+			0,
+		},
+	}
+
+	for _, test := range tests {
+
+		series := barSeries(test.bars...)
+		removed := series.Deduplicate()
+		if removed != test.removed {
+			t.Errorf("%s: removed %d, want %d", test.name, removed, test.removed)
+		}
+
+		if bars := seriesBars(series); !barsEqual(bars, test.expected) {
+			t.Errorf("%s: bars = %+v, want %+v", test.name, bars, test.expected)
+		}
+
+		if int(series.Count) != len(test.expected) || len(series.Volume) != len(test.expected) {
+			t.Errorf("%s: Count %d and arrays of %d, want %d", test.name, series.Count, len(series.Volume), len(test.expected))
+		}
+	}
+}
+
+func TestLast(t *testing.T) {
+
+	pre := barSeries(Bar{Timestamp: 60, Close: 1})
+	regular := barSeries(Bar{Timestamp: 120, Close: 2}, Bar{Timestamp: 180, Close: 3})
+	post := barSeries(Bar{Timestamp: 240, Close: 4})
+
+	tests := []struct {
+		name     string
+		quote    CompanyDailyQuote
+		found    bool
+		expected uint32
+	}{
+		{"empty", CompanyDailyQuote{}, false, 0},
+		{"pre only", CompanyDailyQuote{Pre: pre}, true, 1},
+		{"no post", CompanyDailyQuote{Pre: pre, Regular: regular}, true, 3},
+		{"all sessions", CompanyDailyQuote{Pre: pre, Regular: regular, Post: post}, true, 4},
+	}
+
+	for _, test := range tests {
+		bar, found := test.quote.Last()
+		if found != test.found || bar.Close != test.expected {
+			t.Errorf("%s: Last = %d, %v, want %d, %v", test.name, bar.Close, found, test.expected, test.found)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		series   QuoteSeries
+		found    bool
+		expected Bar
+	}{
+		{"empty", QuoteSeries{}, false, Bar{}},
+		{"one bar", barSeries(Bar{60, 100, 110, 115, 95, 10}), true, Bar{60, 100, 110, 115, 95, 10}},
+		// 最高、最低价出现在中间的报价
+		{"several bars", barSeries(
+			Bar{60, 100, 105, 106, 99, 10},
+			Bar{120, 105, 98, 120, 90, 20},
+			Bar{180, 98, 102, 103, 97, 30},
+		), true, Bar{60, 100, 102, 120, 90, 60}},
+	}
+
+	for _, test := range tests {
+		summary, found := test.series.Summary()
+		if found != test.found || summary != test.expected {
+			t.Errorf("%s: Summary = %+v, %v, want %+v, %v", test.name, summary, found, test.expected, test.found)
+		}
+	}
+}
+
+func TestPartialLast(t *testing.T) {
+
+	session := regularSession(t)
+	minute := func(offset time.Duration) uint32 {
+		return uint32(session.Start.Add(offset).Unix())
+	}
+
+	// 时段在16:00:30结束，16:00的报价只有30秒
+	cutOff := Session{Start: session.Start, End: session.End.Add(30 * time.Second)}
+
+	tests := []struct {
+		name    string
+		session Session
+		last    time.Duration
+		partial bool
+	}{
+		{"full last minute", session, 389 * time.Minute, false},
+		{"cut off mid-minute", cutOff, 390 * time.Minute, true},
+		{"full minute before the cut", cutOff, 389 * time.Minute, false},
+		// 时段之外的报价不算
+		{"outside the session", session, 390 * time.Minute, false},
+	}
+
+	for _, test := range tests {
+
+		series := barSeries(
+			Bar{minute(0), 100, 101, 102, 99, 1000},
+			Bar{minute(test.last), 101, 102, 103, 100, 3},
+		)
+
+		if partial := series.PartialLast(test.session, time.Minute); partial != test.partial {
+			t.Errorf("%s: PartialLast = %v, want %v", test.name, partial, test.partial)
+		}
+
+		trimmed := series.TrimPartialLast(test.session, time.Minute)
+		if trimmed != test.partial {
+			t.Errorf("%s: TrimPartialLast = %v, want %v", test.name, trimmed, test.partial)
+		}
+
+		expected := uint32(2)
+		if test.partial {
+			expected = 1
+		}
+
+		if series.Count != expected || len(series.Volume) != int(expected) || series.Volume[0] != 1000 {
+			t.Errorf("%s: %d bars left after trimming, want %d", test.name, series.Count, expected)
+		}
+	}
+
+	if empty := (QuoteSeries{}); empty.PartialLast(session, time.Minute) || empty.TrimPartialLast(session, time.Minute) {
+		t.Error("an empty series has no partial last bar")
+	}
+}
+
+func TestActiveBounds(t *testing.T) {
+
+	// 开头和结尾几分钟没有成交，中间一分钟没有成交不影响
+	thin := barSeries(
+		Bar{Timestamp: 60, Volume: 0},
+		Bar{Timestamp: 120, Volume: 0},
+		Bar{Timestamp: 180, Volume: 10},
+		Bar{Timestamp: 240, Volume: 0},
+		Bar{Timestamp: 300, Volume: 5},
+		Bar{Timestamp: 360, Volume: 0},
+	)
+
+	tests := []struct {
+		name        string
+		series      QuoteSeries
+		ok          bool
+		first, last int64
+	}{
+		{"thin", thin, true, 180, 300},
+		{"one trade", barSeries(Bar{Timestamp: 60}, Bar{Timestamp: 120, Volume: 1}), true, 120, 120},
+		{"no trades", barSeries(Bar{Timestamp: 60}, Bar{Timestamp: 120}), false, 0, 0},
+		{"empty", QuoteSeries{}, false, 0, 0},
+	}
+
+	for _, test := range tests {
+
+		first, last, ok := test.series.ActiveBounds()
+		if ok != test.ok {
+			t.Errorf("%s: ActiveBounds ok = %v, want %v", test.name, ok, test.ok)
+			continue
+		}
+
+		if ok && (first.Unix() != test.first || last.Unix() != test.last) {
+			t.Errorf("%s: ActiveBounds = %d-%d, want %d-%d", test.name, first.Unix(), last.Unix(), test.first, test.last)
+		}
+	}
+}
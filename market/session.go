@@ -0,0 +1,74 @@
+package market
+
+import (
+	"time"
+)
+
+// Session 交易时段
+type Session struct {
+	Start time.Time // 开始时间(含)
+	End   time.Time // 结束时间(不含)
+}
+
+// Duration 时长
+func (s Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Contains 是否在时段内
+func (s Session) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// PartialBar 从start开始、时长为interval的报价是否在时段结束时被截断
+func (s Session) PartialBar(start time.Time, interval time.Duration) bool {
+	return s.Contains(start) && start.Add(interval).After(s.End)
+}
+
+// MinuteIndex 相对时段开始的分钟序号，开始的一分钟为0，不在时段内时为-1
+func (s Session) MinuteIndex(t time.Time) int {
+
+	if !s.Contains(t) {
+		return -1
+	}
+
+	return int(t.Sub(s.Start) / time.Minute)
+}
+
+// MinuteIndexes 报价序列中每个报价相对时段开始的分钟序号
+func (s Session) MinuteIndexes(series QuoteSeries) []int {
+
+	indexes := make([]int, series.Count)
+	for index := range indexes {
+		indexes[index] = s.MinuteIndex(time.Unix(int64(series.Timestamp[index]), 0))
+	}
+
+	return indexes
+}
+
+// TradingSessions 某天的盘前、正常和盘后交易时段
+type TradingSessions struct {
+	Pre     Session
+	Regular Session
+	Post    Session
+}
+
+// regularOpens 各市场正常交易时段的开盘时间，为市场所在时区的时和分
+var regularOpens = map[string][2]int{
+	"America":  {9, 30},
+	"China":    {9, 30},
+	"HongKong": {9, 30},
+}
+
+// RegularOpen 市场在date当天正常交易时段的开盘时间，日期按市场所在时区，未知的市场为当天0点
+func RegularOpen(_market Market, date time.Time) time.Time {
+
+	location, err := time.LoadLocation(_market.Timezone())
+	if err != nil {
+		location = date.Location()
+	}
+	day := date.In(location)
+
+	open := regularOpens[_market.Name()]
+	return time.Date(day.Year(), day.Month(), day.Day(), open[0], open[1], 0, 0, location)
+}
@@ -0,0 +1,129 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+// regularSession 2017-06-01 纽约的正常交易时段
+func regularSession(t *testing.T) Session {
+	t.Helper()
+
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Session{
+		Start: time.Date(2017, 6, 1, 9, 30, 0, 0, location),
+		End:   time.Date(2017, 6, 1, 16, 0, 0, 0, location),
+	}
+}
+
+func TestSessionContains(t *testing.T) {
+
+	session := regularSession(t)
+	if session.Duration() != 390*time.Minute {
+		t.Errorf("Duration = %s, want 6h30m", session.Duration())
+	}
+
+	tests := []struct {
+		offset   time.Duration
+		expected bool
+	}{
+		{-time.Second, false},
+		{0, true},
+		{389 * time.Minute, true},
+		{390*time.Minute - time.Second, true},
+		// 结束时间不含
+		{390 * time.Minute, false},
+	}
+
+	for _, test := range tests {
+		if contains := session.Contains(session.Start.Add(test.offset)); contains != test.expected {
+			t.Errorf("Contains(start+%s) = %v, want %v", test.offset, contains, test.expected)
+		}
+	}
+}
+
+func TestMinuteIndex(t *testing.T) {
+
+	session := regularSession(t)
+
+	tests := []struct {
+		offset   time.Duration
+		expected int
+	}{
+		{-time.Minute, -1},
+		{0, 0},
+		{30 * time.Second, 0},
+		{29 * time.Minute, 29},
+		{389 * time.Minute, 389},
+		{390 * time.Minute, -1},
+	}
+
+	for _, test := range tests {
+		if index := session.MinuteIndex(session.Start.Add(test.offset)); index != test.expected {
+			t.Errorf("MinuteIndex(+%s) = %d, want %d", test.offset, index, test.expected)
+		}
+	}
+
+	// 含盘前和盘后的报价
+	series := barSeries(
+		Bar{Timestamp: uint32(session.Start.Add(-5 * time.Minute).Unix())},
+		Bar{Timestamp: uint32(session.Start.Unix())},
+		Bar{Timestamp: uint32(session.Start.Add(30 * time.Minute).Unix())},
+		Bar{Timestamp: uint32(session.End.Add(-time.Minute).Unix())},
+		Bar{Timestamp: uint32(session.End.Unix())},
+	)
+
+	indexes := session.MinuteIndexes(series)
+	expected := []int{-1, 0, 30, 389, -1}
+	if len(indexes) != len(expected) {
+		t.Fatalf("MinuteIndexes = %v, want %v", indexes, expected)
+	}
+
+	for index := range expected {
+		if indexes[index] != expected[index] {
+			t.Errorf("MinuteIndexes[%d] = %d, want %d", index, indexes[index], expected[index])
+		}
+	}
+}
+
+// unknownMarket 没有开盘时间的市场
+type unknownMarket struct {
+	America
+}
+
+// Name 名称
+func (m unknownMarket) Name() string {
+	return "Unknown"
+}
+
+func TestRegularOpen(t *testing.T) {
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 夏令时开始当天仍为当地时间9:30，日期按市场所在时区
+	tests := []struct {
+		name     string
+		market   Market
+		date     time.Time
+		expected string
+	}{
+		{"america", America{}, time.Date(2017, 6, 1, 0, 0, 0, 0, newYork), "2017-06-01 09:30 EDT"},
+		{"dst", America{}, time.Date(2017, 3, 12, 0, 0, 0, 0, newYork), "2017-03-12 09:30 EDT"},
+		{"standard time", America{}, time.Date(2017, 3, 10, 0, 0, 0, 0, newYork), "2017-03-10 09:30 EST"},
+		{"hongkong", HongKong{}, time.Date(2017, 6, 1, 0, 0, 0, 0, newYork), "2017-06-01 09:30 HKT"},
+		{"unknown", unknownMarket{}, time.Date(2017, 6, 1, 0, 0, 0, 0, newYork), "2017-06-01 00:00 EDT"},
+	}
+
+	for _, test := range tests {
+		if open := RegularOpen(test.market, test.date).Format("2006-01-02 15:04 MST"); open != test.expected {
+			t.Errorf("%s: RegularOpen = %s, want %s", test.name, open, test.expected)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package market
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// ShardCompanies 按市场名称和公司代码的哈希将上市公司稳定地分成shardCount份，返回第shardIndex份(从0开始)
+// 各份互不重叠，同一公司总是分到同一份，多个记录器实例各取一份即可无需协调地分担抓取
+func ShardCompanies(_market Market, companies []Company, shardIndex, shardCount int) []Company {
+
+	if shardCount <= 1 {
+		return companies
+	}
+
+	if shardIndex < 0 || shardIndex >= shardCount {
+		return nil
+	}
+
+	var shard []Company
+	for _, company := range companies {
+
+		hash := fnv.New32a()
+		hash.Write([]byte(strings.ToLower(_market.Name()) + ":" + company.Code))
+
+		if int(hash.Sum32()%uint32(shardCount)) == shardIndex {
+			shard = append(shard, company)
+		}
+	}
+
+	return shard
+}
@@ -0,0 +1,70 @@
+package market
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShardCompanies(t *testing.T) {
+
+	companies := make([]Company, 300)
+	for index := range companies {
+		companies[index] = Company{Code: fmt.Sprintf("C%03d", index)}
+	}
+
+	const shardCount = 4
+
+	// 各份互不重叠，合起来是全部公司
+	owner := make(map[string]int)
+	for shardIndex := 0; shardIndex < shardCount; shardIndex++ {
+
+		shard := ShardCompanies(America{}, companies, shardIndex, shardCount)
+		if len(shard) == 0 {
+			t.Errorf("shard %d is empty", shardIndex)
+		}
+
+		for _, company := range shard {
+			if previous, found := owner[company.Code]; found {
+				t.Errorf("%s is in shards %d and %d", company.Code, previous, shardIndex)
+			}
+			owner[company.Code] = shardIndex
+		}
+	}
+
+	if len(owner) != len(companies) {
+		t.Errorf("shards cover %d companies, want %d", len(owner), len(companies))
+	}
+
+	// 公司列表顺序和其他公司变化时分配不变
+	reversed := make([]Company, 0, len(companies))
+	for index := len(companies) - 1; index >= 10; index-- {
+		reversed = append(reversed, companies[index])
+	}
+
+	for shardIndex := 0; shardIndex < shardCount; shardIndex++ {
+		for _, company := range ShardCompanies(America{}, reversed, shardIndex, shardCount) {
+			if owner[company.Code] != shardIndex {
+				t.Errorf("%s moved from shard %d to %d", company.Code, owner[company.Code], shardIndex)
+			}
+		}
+	}
+}
+
+func TestShardCompaniesBounds(t *testing.T) {
+
+	companies := []Company{{Code: "A"}, {Code: "B"}}
+
+	if shard := ShardCompanies(America{}, companies, 0, 1); len(shard) != 2 {
+		t.Errorf("single shard = %v, want every company", shard)
+	}
+
+	if shard := ShardCompanies(America{}, companies, 0, 0); len(shard) != 2 {
+		t.Errorf("no sharding = %v, want every company", shard)
+	}
+
+	for _, shardIndex := range []int{-1, 3} {
+		if shard := ShardCompanies(America{}, companies, shardIndex, 3); shard != nil {
+			t.Errorf("shard %d of 3 = %v, want none", shardIndex, shard)
+		}
+	}
+}
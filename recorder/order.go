@@ -0,0 +1,29 @@
+package recorder
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// dispatchOrder 公司的抓取顺序
+// 设置了随机种子时先按种子和日期打乱，相同种子和日期的顺序总是相同，不同日期的顺序不同，避免限流时总是同一批公司失败
+// 之后按优先级稳定排序，优先级高的公司先抓取
+func dispatchOrder(companies []market.Company, seed *int64, date time.Time) []market.Company {
+
+	ordered := make([]market.Company, len(companies))
+	copy(ordered, companies)
+
+	if seed != nil {
+		random := rand.New(rand.NewSource(*seed ^ date.Unix()))
+		random.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	}
+
+	sort.Stable(market.CompanyPriorityList(ordered))
+
+	return ordered
+}
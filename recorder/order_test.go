@@ -0,0 +1,138 @@
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+	"github.com/nzai/stockrecorder/store"
+)
+
+// orderSource 逐家抓取并记录抓取顺序的数据源
+type orderSource struct {
+	mutex *sync.Mutex
+	codes *[]string
+}
+
+func (s orderSource) Expiration() time.Duration { return time.Hour * 24 }
+
+func (s orderSource) ParallelMax(_market market.Market) int { return 1 }
+
+func (s orderSource) RetryCount() int { return 1 }
+
+func (s orderSource) RetryInterval() time.Duration { return 0 }
+
+// Crawl 记录抓取的公司
+func (s orderSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	s.mutex.Lock()
+	*s.codes = append(*s.codes, company.Code)
+	s.mutex.Unlock()
+
+	return &market.CompanyDailyQuote{Company: company}, nil
+}
+
+// codes 公司代码，以逗号分隔
+func codes(companies []market.Company) string {
+
+	values := make([]string, len(companies))
+	for index, company := range companies {
+		values[index] = company.Code
+	}
+
+	return strings.Join(values, ",")
+}
+
+// priorityCompanies 长尾公司中间夹着几家重点关注的公司
+func priorityCompanies() []market.Company {
+	return []market.Company{
+		{Code: "T1"},
+		{Code: "W1", Priority: 10},
+		{Code: "T2"},
+		{Code: "T3"},
+		{Code: "W2", Priority: 5},
+		{Code: "W3", Priority: 10},
+		{Code: "T4"},
+	}
+}
+
+func TestDispatchPriority(t *testing.T) {
+
+	companies := priorityCompanies()
+	date := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// 优先级高的先抓取，相同优先级保持原有顺序
+	if order := codes(dispatchOrder(companies, nil, date)); order != "W1,W3,W2,T1,T2,T3,T4" {
+		t.Errorf("dispatchOrder = %s, want W1,W3,W2,T1,T2,T3,T4", order)
+	}
+
+	// 不修改原列表
+	if order := codes(companies); order != "T1,W1,T2,T3,W2,W3,T4" {
+		t.Errorf("companies reordered to %s", order)
+	}
+}
+
+func TestDispatchShuffle(t *testing.T) {
+
+	// 足够多的长尾公司，不同种子或日期碰巧得到相同顺序几乎不可能
+	companies := priorityCompanies()
+	for index := 5; index <= 20; index++ {
+		companies = append(companies, market.Company{Code: fmt.Sprintf("T%d", index)})
+	}
+
+	seed, other := int64(7), int64(8)
+	date := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	order := codes(dispatchOrder(companies, &seed, date))
+
+	if again := codes(dispatchOrder(companies, &seed, date)); again != order {
+		t.Errorf("same seed and date: %s, then %s", order, again)
+	}
+
+	if unseeded := codes(dispatchOrder(companies, nil, date)); order == unseeded {
+		t.Errorf("seeded order %s is the list order", order)
+	}
+
+	if different := codes(dispatchOrder(companies, &other, date)); different == order {
+		t.Errorf("seeds %d and %d both give %s", seed, other, order)
+	}
+
+	if different := codes(dispatchOrder(companies, &seed, date.AddDate(0, 0, 1))); different == order {
+		t.Errorf("%s and the next day both give %s", date.Format("2006-01-02"), order)
+	}
+
+	// 打乱后优先级高的仍然先抓取，相同优先级之间的顺序可以变化
+	if watchlist := strings.Join(strings.Split(order, ",")[:3], ","); watchlist != "W1,W3,W2" && watchlist != "W3,W1,W2" {
+		t.Errorf("seeded order %s does not start with the watchlist", order)
+	}
+}
+
+func TestCrawlPriority(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var crawled []string
+	mr := marketRecorder{
+		source: orderSource{mutex: new(sync.Mutex), codes: &crawled},
+		store:  store.NewFileSystem(store.FileSystemConfig{StoreRoot: root}),
+		Market: market.America{},
+		pauser: newPauser(),
+	}
+
+	location, _ := time.LoadLocation(mr.Timezone())
+	if err = mr.crawl(priorityCompanies(), time.Date(2017, 6, 1, 0, 0, 0, 0, location)); err != nil {
+		t.Fatalf("crawl: %v", err)
+	}
+
+	if order := strings.Join(crawled, ","); order != "W1,W3,W2,T1,T2,T3,T4" {
+		t.Errorf("crawled %s, want the watchlist before the long tail", order)
+	}
+}
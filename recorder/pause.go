@@ -0,0 +1,50 @@
+package recorder
+
+import (
+	"sync"
+)
+
+// pauser 暂停控制
+type pauser struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	paused bool
+}
+
+// newPauser 新建暂停控制
+func newPauser() *pauser {
+	p := &pauser{}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+// Pause 暂停
+func (p *pauser) Pause() {
+	p.mutex.Lock()
+	p.paused = true
+	p.mutex.Unlock()
+}
+
+// Resume 恢复
+func (p *pauser) Resume() {
+	p.mutex.Lock()
+	p.paused = false
+	p.mutex.Unlock()
+	p.cond.Broadcast()
+}
+
+// Paused 是否已暂停
+func (p *pauser) Paused() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.paused
+}
+
+// Wait 暂停时阻塞，直到恢复
+func (p *pauser) Wait() {
+	p.mutex.Lock()
+	for p.paused {
+		p.cond.Wait()
+	}
+	p.mutex.Unlock()
+}
@@ -0,0 +1,92 @@
+package recorder
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+	"github.com/nzai/stockrecorder/store"
+)
+
+func TestPauser(t *testing.T) {
+
+	p := newPauser()
+	p.Wait() // 未暂停时不阻塞
+
+	p.Pause()
+	if !p.Paused() {
+		t.Fatal("Paused = false after Pause")
+	}
+
+	released := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(released)
+	}()
+
+	select {
+	case <-released:
+		t.Fatal("Wait returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	p.Resume()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after Resume")
+	}
+
+	if p.Paused() {
+		t.Error("Paused = true after Resume")
+	}
+}
+
+func TestRecorderPause(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var dates []time.Time
+	source := dateSource{mutex: new(sync.Mutex), dates: &dates}
+	r := NewRecorder(source, store.NewFileSystem(store.FileSystemConfig{StoreRoot: root}), market.America{})
+	mr := marketRecorder{source: source, store: r.store, Market: market.America{}, pauser: r.pauser}
+
+	location, _ := time.LoadLocation(mr.Timezone())
+	date := time.Date(2017, 6, 1, 0, 0, 0, 0, location)
+
+	// 暂停时不开始新的抓取
+	r.Pause()
+	done := make(chan error, 1)
+	go func() {
+		done <- mr.crawl([]market.Company{{Code: "A"}, {Code: "B"}}, date)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	source.mutex.Lock()
+	crawled := len(dates)
+	source.mutex.Unlock()
+	if crawled != 0 {
+		t.Fatalf("crawled %d companies while paused, want 0", crawled)
+	}
+
+	r.Resume()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("crawl: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("crawl did not finish after Resume")
+	}
+
+	if len(dates) != 2 {
+		t.Errorf("crawled %d companies after Resume, want 2", len(dates))
+	}
+}
@@ -1,8 +1,10 @@
 package recorder
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,11 +22,33 @@ type Recorder struct {
 	source  source.Source   // 数据源
 	store   store.Store     // 存储
 	markets []market.Market // 市场
+	pauser  *pauser         // 暂停控制
+	shuffle *int64          // 打乱抓取顺序的随机种子，为nil时按上市公司列表顺序抓取
 }
 
 // NewRecorder 新建Recorder
 func NewRecorder(source source.Source, store store.Store, markets ...market.Market) *Recorder {
-	return &Recorder{source, store, markets}
+	return &Recorder{source: source, store: store, markets: markets, pauser: newPauser()}
+}
+
+// SetShuffleSeed 按随机种子打乱每天的抓取顺序，相同种子和日期的顺序相同，需要在RunAndWait之前调用
+func (r *Recorder) SetShuffleSeed(seed int64) {
+	r.shuffle = &seed
+}
+
+// Pause 暂停抓取，已开始的抓取继续完成，不再开始新的抓取
+func (r Recorder) Pause() {
+	r.pauser.Pause()
+}
+
+// Resume 恢复抓取
+func (r Recorder) Resume() {
+	r.pauser.Resume()
+}
+
+// Paused 是否已暂停
+func (r Recorder) Paused() bool {
+	return r.pauser.Paused()
 }
 
 // RunAndWait 执行
@@ -35,7 +59,7 @@ func (r Recorder) RunAndWait() {
 	for _, m := range r.markets {
 		go func(m market.Market) {
 			// 构造记录器
-			mr := marketRecorder{r.source, r.store, m}
+			mr := marketRecorder{r.source, r.store, m, r.pauser, r.shuffle}
 			// 启动
 			mr.RunAndWait()
 			wg.Done()
@@ -50,6 +74,8 @@ type marketRecorder struct {
 	source        source.Source // 数据源
 	store         store.Store   // 存储
 	market.Market               // 市场
+	pauser        *pauser       // 暂停控制
+	shuffleSeed   *int64        // 打乱抓取顺序的随机种子
 }
 
 // RunAndWait 启动市场记录器
@@ -74,7 +100,7 @@ func (mr marketRecorder) RunAndWait() {
 	for {
 		log.Printf("[%s] 定时任务已启动，将于%s后激活下一次任务", mr.Name(), duration.String())
 		<-time.After(duration)
-		yesterday := mr.marketNow().AddDate(0, 0, -1)
+		yesterday := mr.dateZero(mr.marketNow().AddDate(0, 0, -1))
 		log.Printf("[%s] 获取%s的数据开始", mr.Name(), yesterday.Format(datePattern))
 		err := mr.crawlYesterdayData(yesterday)
 		if err != nil {
@@ -112,11 +138,17 @@ func (mr marketRecorder) durationToNextDay(now time.Time) time.Duration {
 	return marketTomorrowZero.Sub(now)
 }
 
+// dateZero 市场所处时区当天0点
+func (mr marketRecorder) dateZero(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
 // crawlHistoryData 抓取历史数据
 func (mr marketRecorder) crawlHistoryData(todayZero time.Time) error {
 
-	// 起始日期(含)
-	date := todayZero.Add(-mr.source.Expiration())
+	// 起始日期(含)，按日历天数回退，避免跨越夏令时切换时偏离0点
+	date := todayZero.AddDate(0, 0, -int(mr.source.Expiration()/(time.Hour*24)))
 	log.Printf("[%s]抓取历史数据起始日期: %s  结束日期: %s", mr.Name(), date.Format(datePattern), todayZero.Format(datePattern))
 
 	// 获取上市公司
@@ -172,11 +204,16 @@ func (mr marketRecorder) crawlYesterdayData(yesterday time.Time) error {
 // crawl 抓取指定日期的市场报价
 func (mr marketRecorder) crawl(companies []market.Company, date time.Time) error {
 
-	ch := make(chan bool, mr.source.ParallelMax())
+	// 下载字节数限制等按次计算，各市场、各日期互不影响
+	crawler := source.ForRun(mr.source)
+
+	ch := make(chan bool, crawler.ParallelMax(mr.Market))
 	defer close(ch)
 
 	var wg sync.WaitGroup
-	wg.Add(len(companies))
+
+	// 多个抓取协程共同写入报价列表
+	var mutex sync.Mutex
 
 	_, offset := date.Zone()
 
@@ -186,24 +223,61 @@ func (mr marketRecorder) crawl(companies []market.Company, date time.Time) error
 		UTCOffset: offset,
 	}
 
-	for _, company := range companies {
+	ordered := dispatchOrder(companies, mr.shuffleSeed, date)
+
+	// 超过下载字节数限制后未抓取的公司
+	var unfetched []string
+	exhausted, future := false, false
+
+	for index, company := range ordered {
 
+		// 暂停时不再开始新的抓取
+		mr.pauser.Wait()
+
+		// 限流，先占用名额再开始抓取
+		ch <- false
+
+		mutex.Lock()
+		if exhausted {
+			for _, rest := range ordered[index:] {
+				unfetched = append(unfetched, rest.Code)
+			}
+			mutex.Unlock()
+			<-ch
+			break
+		}
+		mutex.Unlock()
+
+		wg.Add(1)
 		go func(_market market.Market, _company market.Company, _date time.Time) {
-			quote, err := mr.source.Crawl(_market, _company, _date)
+			quote, err := crawler.Crawl(_market, _company, _date)
+			mutex.Lock()
 			if err == nil {
 				dailyQuote.Quotes = append(dailyQuote.Quotes, *quote)
+			} else if errors.Is(err, source.ErrByteBudgetExceeded) {
+				exhausted = true
+				unfetched = append(unfetched, _company.Code)
+			} else if errors.Is(err, source.ErrFutureDate) {
+				future = true
 			}
+			mutex.Unlock()
 
 			<-ch
 			wg.Done()
 		}(mr.Market, company, date)
-
-		// 限流
-		ch <- false
 	}
 	//	阻塞，直到抓取所有
 	wg.Wait()
 
+	// 不保存未来日期和不完整的一天，以免被当作已记录而不再抓取
+	if future {
+		return fmt.Errorf("[%s] %s: %w", mr.Market.Name(), date.Format(datePattern), source.ErrFutureDate)
+	}
+
+	if exhausted {
+		return fmt.Errorf("[%s] 抓取%s的分时数据时%w，%d家上市公司未抓取: %s", mr.Market.Name(), date.Format(datePattern), source.ErrByteBudgetExceeded, len(unfetched), strings.Join(unfetched, ","))
+	}
+
 	// 保存
 	err := mr.store.Save(dailyQuote)
 	if err != nil {
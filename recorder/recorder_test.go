@@ -0,0 +1,411 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+	"github.com/nzai/stockrecorder/source"
+	"github.com/nzai/stockrecorder/store"
+)
+
+// budgetSource 每次运行最多抓取limit家公司的数据源
+type budgetSource struct {
+	limit   int32
+	crawled *int32 // 本次运行已抓取的公司数
+	calls   *int32 // 所有运行的Crawl调用次数
+}
+
+// ForRun 每次运行重新计数
+func (s budgetSource) ForRun() source.Source {
+	return budgetSource{limit: s.limit, crawled: new(int32), calls: s.calls}
+}
+
+func (s budgetSource) Expiration() time.Duration { return time.Hour * 24 }
+
+func (s budgetSource) ParallelMax(_market market.Market) int { return 1 }
+
+func (s budgetSource) RetryCount() int { return 1 }
+
+func (s budgetSource) RetryInterval() time.Duration { return 0 }
+
+// Crawl 超过限制后返回ErrByteBudgetExceeded
+func (s budgetSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	atomic.AddInt32(s.calls, 1)
+	if atomic.AddInt32(s.crawled, 1) > s.limit {
+		return nil, source.ErrByteBudgetExceeded
+	}
+
+	return &market.CompanyDailyQuote{Company: company}, nil
+}
+
+func TestCrawlByteBudget(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s := store.NewFileSystem(store.FileSystemConfig{StoreRoot: root})
+	companies := make([]market.Company, 10)
+	for index := range companies {
+		companies[index] = market.Company{Code: fmt.Sprintf("C%02d", index)}
+	}
+
+	mr := marketRecorder{
+		source: budgetSource{limit: 3, crawled: new(int32), calls: new(int32)},
+		store:  s,
+		Market: market.America{},
+		pauser: newPauser(),
+	}
+
+	location, _ := time.LoadLocation(mr.Timezone())
+
+	// 每次运行单独计算限制，前一个日期用完不影响后一个日期
+	for _, date := range []time.Time{
+		time.Date(2017, 6, 1, 0, 0, 0, 0, location),
+		time.Date(2017, 6, 2, 0, 0, 0, 0, location),
+	} {
+
+		calls := mr.source.(budgetSource).calls
+		atomic.StoreInt32(calls, 0)
+
+		err = mr.crawl(companies, date)
+		if !errors.Is(err, source.ErrByteBudgetExceeded) {
+			t.Fatalf("crawl %s = %v, want ErrByteBudgetExceeded", date.Format(datePattern), err)
+		}
+
+		// 逐家抓取，第4家公司用完限制后其余不再抓取
+		if count := atomic.LoadInt32(calls); count != 4 {
+			t.Errorf("crawl %s called Crawl %d times, want 4", date.Format(datePattern), count)
+		}
+
+		if !strings.Contains(err.Error(), "上市公司未抓取") || !strings.Contains(err.Error(), "C09") {
+			t.Errorf("crawl %s = %v, want the unfetched companies listed", date.Format(datePattern), err)
+		}
+
+		// 不完整的一天不保存
+		exists, err := s.Exists(mr.Market, date)
+		if err != nil || exists {
+			t.Errorf("Exists %s = %v, %v, want false", date.Format(datePattern), exists, err)
+		}
+	}
+}
+
+// dateSource 记录抓取日期的数据源
+type dateSource struct {
+	mutex *sync.Mutex
+	dates *[]time.Time
+}
+
+// Expiration 最早抓取5天前的数据
+func (s dateSource) Expiration() time.Duration { return time.Hour * 24 * 5 }
+
+func (s dateSource) ParallelMax(_market market.Market) int { return 1 }
+
+func (s dateSource) RetryCount() int { return 1 }
+
+func (s dateSource) RetryInterval() time.Duration { return 0 }
+
+// Crawl 记录抓取日期
+func (s dateSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	s.mutex.Lock()
+	*s.dates = append(*s.dates, date)
+	s.mutex.Unlock()
+
+	return &market.CompanyDailyQuote{Company: company}, nil
+}
+
+// fixedMarket 上市公司固定的美国市场
+type fixedMarket struct {
+	market.America
+	companies []market.Company
+}
+
+// Companies 上市公司
+func (m fixedMarket) Companies() ([]market.Company, error) {
+	return m.companies, nil
+}
+
+func TestCrawlHistoryAcrossDST(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var dates []time.Time
+	mr := marketRecorder{
+		source: dateSource{mutex: new(sync.Mutex), dates: &dates},
+		store:  store.NewFileSystem(store.FileSystemConfig{StoreRoot: root}),
+		Market: fixedMarket{companies: []market.Company{{Code: "AAPL"}}},
+		pauser: newPauser(),
+	}
+
+	location, err := time.LoadLocation(mr.Timezone())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2017-03-12 美国开始夏令时，当天只有23小时
+	todayZero := time.Date(2017, 3, 14, 0, 0, 0, 0, location)
+	if err = mr.crawlHistoryData(todayZero); err != nil {
+		t.Fatalf("crawlHistoryData: %v", err)
+	}
+
+	if len(dates) != 5 {
+		t.Fatalf("crawled %d days, want 5: %v", len(dates), dates)
+	}
+
+	for index, date := range dates {
+
+		expected := time.Date(2017, 3, 9+index, 0, 0, 0, 0, location)
+		if !date.Equal(expected) || date.Hour() != 0 {
+			t.Errorf("day %d = %s, want %s", index, date, expected)
+		}
+	}
+
+	// 夏令时开始前一天到下一个0点只有23小时
+	now := time.Date(2017, 3, 12, 0, 0, 0, 0, location)
+	if duration := mr.durationToNextDay(now); duration != 23*time.Hour {
+		t.Errorf("durationToNextDay(%s) = %s, want 23h", now, duration)
+	}
+
+	// 夏令时切换当天的任意时刻都归到当天0点
+	noon := time.Date(2017, 3, 12, 12, 0, 0, 0, location)
+	if zero := mr.dateZero(noon); !zero.Equal(now) {
+		t.Errorf("dateZero(%s) = %s, want %s", noon, zero, now)
+	}
+}
+
+// parallelSource 并发返回报价的数据源
+type parallelSource struct{}
+
+func (s parallelSource) Expiration() time.Duration { return time.Hour * 24 }
+
+func (s parallelSource) ParallelMax(_market market.Market) int { return 8 }
+
+func (s parallelSource) RetryCount() int { return 1 }
+
+func (s parallelSource) RetryInterval() time.Duration { return 0 }
+
+// Crawl 返回只有一个报价的公司
+func (s parallelSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	timestamp := uint32(date.Add(10 * time.Hour).Unix())
+	return &market.CompanyDailyQuote{
+		Company: company,
+		Regular: market.QuoteSeries{
+			Count:     1,
+			Timestamp: []uint32{timestamp},
+			Open:      []uint32{100},
+			Close:     []uint32{100},
+			Max:       []uint32{100},
+			Min:       []uint32{100},
+			Volume:    []uint32{1},
+		},
+	}, nil
+}
+
+func TestCrawlConcurrentAppends(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s := store.NewFileSystem(store.FileSystemConfig{StoreRoot: root})
+	mr := marketRecorder{source: parallelSource{}, store: s, Market: market.America{}, pauser: newPauser()}
+
+	companies := make([]market.Company, 200)
+	for index := range companies {
+		companies[index] = market.Company{Code: fmt.Sprintf("C%03d", index)}
+	}
+
+	location, _ := time.LoadLocation(mr.Timezone())
+	date := time.Date(2017, 6, 1, 0, 0, 0, 0, location)
+
+	// 多个抓取协程同时写入报价列表，不能丢失
+	if err = mr.crawl(companies, date); err != nil {
+		t.Fatalf("crawl: %v", err)
+	}
+
+	quote, err := s.Load(mr.Market, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(quote.Quotes) != len(companies) {
+		t.Errorf("saved %d quotes, want %d", len(quote.Quotes), len(companies))
+	}
+
+	for _, company := range companies {
+		if _, found := quote.Find(company.Code); !found {
+			t.Errorf("%s missing from the saved day", company.Code)
+		}
+	}
+}
+
+// cappedSource 按市场限制并发并记录各市场的最大并发数的数据源
+type cappedSource struct {
+	caps   map[string]int
+	active map[string]*int32
+	peak   map[string]*int32
+}
+
+func newCappedSource(caps map[string]int) cappedSource {
+
+	s := cappedSource{caps: caps, active: make(map[string]*int32), peak: make(map[string]*int32)}
+	for name := range caps {
+		s.active[name], s.peak[name] = new(int32), new(int32)
+	}
+
+	return s
+}
+
+func (s cappedSource) Expiration() time.Duration { return time.Hour * 24 }
+
+func (s cappedSource) ParallelMax(_market market.Market) int { return s.caps[_market.Name()] }
+
+func (s cappedSource) RetryCount() int { return 1 }
+
+func (s cappedSource) RetryInterval() time.Duration { return 0 }
+
+// Crawl 记录市场的并发数
+func (s cappedSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	active, peak := s.active[_market.Name()], s.peak[_market.Name()]
+
+	current := atomic.AddInt32(active, 1)
+	defer atomic.AddInt32(active, -1)
+
+	for {
+		old := atomic.LoadInt32(peak)
+		if current <= old || atomic.CompareAndSwapInt32(peak, old, current) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	return &market.CompanyDailyQuote{Company: company}, nil
+}
+
+func TestCrawlMarketParallelMax(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s := store.NewFileSystem(store.FileSystemConfig{StoreRoot: root})
+	crawler := newCappedSource(map[string]int{"America": 2, "China": 4})
+
+	companies := make([]market.Company, 20)
+	for index := range companies {
+		companies[index] = market.Company{Code: fmt.Sprintf("C%02d", index)}
+	}
+
+	// 两个市场同时抓取，各自按自己的并发数
+	var wg sync.WaitGroup
+	for _, _market := range []market.Market{market.America{}, market.China{}} {
+
+		mr := marketRecorder{source: crawler, store: s, Market: _market, pauser: newPauser()}
+		location, _ := time.LoadLocation(mr.Timezone())
+
+		wg.Add(1)
+		go func(mr marketRecorder, date time.Time) {
+			defer wg.Done()
+
+			if err := mr.crawl(companies, date); err != nil {
+				t.Errorf("[%s] crawl: %v", mr.Name(), err)
+			}
+		}(mr, time.Date(2017, 6, 1, 0, 0, 0, 0, location))
+	}
+	wg.Wait()
+
+	for name, limit := range crawler.caps {
+		if peak := atomic.LoadInt32(crawler.peak[name]); peak != int32(limit) {
+			t.Errorf("[%s] peak concurrency = %d, want its own cap %d", name, peak, limit)
+		}
+	}
+}
+
+func TestCrawlFutureDate(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// 日期还没有开始时雅虎数据源不发出请求，经过熔断和备用数据源后错误不变
+	yahoo := source.NewYahooFinance(source.YahooFinanceConfig{})
+	s := store.NewFileSystem(store.FileSystemConfig{StoreRoot: root})
+	mr := marketRecorder{
+		source: source.FallbackChain(source.NewCircuitBreaker(yahoo, 2, time.Minute), yahoo),
+		store:  s,
+		Market: market.America{},
+		pauser: newPauser(),
+	}
+
+	location, _ := time.LoadLocation(mr.Timezone())
+	now := time.Now().In(location)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+
+	err = mr.crawl([]market.Company{{Code: "AAPL"}, {Code: "MSFT"}}, tomorrow)
+	if !errors.Is(err, source.ErrFutureDate) {
+		t.Fatalf("crawl %s = %v, want ErrFutureDate", tomorrow.Format(datePattern), err)
+	}
+
+	// 不保存，以免被当作已记录
+	exists, err := s.Exists(mr.Market, tomorrow)
+	if err != nil || exists {
+		t.Errorf("Exists %s = %v, %v, want false", tomorrow.Format(datePattern), exists, err)
+	}
+}
+
+func TestCrawlEmptyFallback(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// 没有数据源时每家公司都失败，单个公司失败不影响保存，但不能卡住
+	mr := marketRecorder{
+		source: source.FallbackChain(),
+		store:  store.NewFileSystem(store.FileSystemConfig{StoreRoot: root}),
+		Market: market.America{},
+		pauser: newPauser(),
+	}
+
+	location, _ := time.LoadLocation(mr.Timezone())
+	done := make(chan error, 1)
+	go func() {
+		done <- mr.crawl([]market.Company{{Code: "AAPL"}, {Code: "MSFT"}}, time.Date(2017, 6, 1, 0, 0, 0, 0, location))
+	}()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("crawl without sources: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("crawl without sources blocked")
+	}
+}
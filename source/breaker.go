@@ -0,0 +1,152 @@
+package source
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+var (
+	// ErrCircuitOpen 熔断中，跳过抓取
+	ErrCircuitOpen = errors.New("熔断中，跳过抓取")
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	// BreakerClosed 正常
+	BreakerClosed BreakerState = iota
+	// BreakerOpen 熔断，跳过抓取
+	BreakerOpen
+	// BreakerHalfOpen 冷却结束，允许一次试探
+	BreakerHalfOpen
+)
+
+// String 状态名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker 单个上市公司的熔断记录
+type breaker struct {
+	failures int       // 连续失败次数
+	openedAt time.Time // 熔断时间
+	probing  bool      // 是否正在试探
+}
+
+// CircuitBreaker 按上市公司熔断的数据源，连续失败达到阈值后在冷却时间内跳过该公司
+type CircuitBreaker struct {
+	Source
+	threshold int
+	cooldown  time.Duration
+	mutex     *sync.Mutex
+	breakers  map[string]*breaker
+}
+
+// NewCircuitBreaker 新建按上市公司熔断的数据源
+func NewCircuitBreaker(source Source, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Source:    source,
+		threshold: threshold,
+		cooldown:  cooldown,
+		mutex:     new(sync.Mutex),
+		breakers:  make(map[string]*breaker),
+	}
+}
+
+// ForRun 内部数据源换成本次运行使用的数据源，熔断记录在各次运行间共享
+func (c *CircuitBreaker) ForRun() Source {
+
+	run := *c
+	run.Source = ForRun(c.Source)
+
+	return &run
+}
+
+// breakerKey 熔断记录键
+func (c *CircuitBreaker) breakerKey(_market market.Market, company market.Company) string {
+	return strings.ToLower(_market.Name()) + ":" + company.Code
+}
+
+// state 当前状态，调用前须加锁
+func (c *CircuitBreaker) state(b *breaker, now time.Time) BreakerState {
+
+	if b == nil || b.failures < c.threshold {
+		return BreakerClosed
+	}
+
+	if now.Sub(b.openedAt) < c.cooldown {
+		return BreakerOpen
+	}
+
+	return BreakerHalfOpen
+}
+
+// State 上市公司的熔断状态
+func (c *CircuitBreaker) State(_market market.Market, company market.Company) BreakerState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.state(c.breakers[c.breakerKey(_market, company)], time.Now())
+}
+
+// Crawl 获取公司每天的报价，熔断中直接返回ErrCircuitOpen
+func (c *CircuitBreaker) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	key := c.breakerKey(_market, company)
+
+	c.mutex.Lock()
+	b, found := c.breakers[key]
+	if !found {
+		b = &breaker{}
+		c.breakers[key] = b
+	}
+
+	switch c.state(b, time.Now()) {
+	case BreakerOpen:
+		c.mutex.Unlock()
+		return nil, ErrCircuitOpen
+	case BreakerHalfOpen:
+		// 同时只允许一次试探
+		if b.probing {
+			c.mutex.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	c.mutex.Unlock()
+
+	quote, err := c.Source.Crawl(_market, company, date)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		return quote, nil
+	}
+
+	// 日期还没有开始不是数据源的故障
+	if err == ErrFutureDate {
+		return nil, err
+	}
+
+	b.failures++
+	if b.failures >= c.threshold {
+		b.openedAt = time.Now()
+	}
+
+	return nil, err
+}
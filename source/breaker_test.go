@@ -0,0 +1,107 @@
+package source
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// switchSource 结果可以随时切换的数据源
+type switchSource struct {
+	stubSource
+	failing *bool
+}
+
+// Crawl failing为true时失败
+func (s switchSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	*s.calls++
+	if *s.failing {
+		return nil, errors.New("down")
+	}
+
+	return &market.CompanyDailyQuote{Company: company}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+
+	var calls int
+	failing := true
+	breaker := NewCircuitBreaker(switchSource{stubSource{calls: &calls}, &failing}, 2, 50*time.Millisecond)
+
+	aapl := market.Company{Code: "AAPL"}
+	msft := market.Company{Code: "MSFT"}
+	state := func() BreakerState { return breaker.State(market.America{}, aapl) }
+
+	// 连续失败未达到阈值时保持正常
+	breaker.Crawl(market.America{}, aapl, time.Now())
+	if state() != BreakerClosed {
+		t.Fatalf("state after 1 failure = %s, want closed", state())
+	}
+
+	// 达到阈值后熔断，跳过抓取
+	breaker.Crawl(market.America{}, aapl, time.Now())
+	if state() != BreakerOpen {
+		t.Fatalf("state after 2 failures = %s, want open", state())
+	}
+
+	if _, err := breaker.Crawl(market.America{}, aapl, time.Now()); err != ErrCircuitOpen || calls != 2 {
+		t.Errorf("Crawl while open = %v with %d calls, want ErrCircuitOpen without calling the source", err, calls)
+	}
+
+	// 按上市公司熔断，其他公司不受影响
+	if breaker.State(market.America{}, msft) != BreakerClosed {
+		t.Errorf("MSFT state = %s, want closed", breaker.State(market.America{}, msft))
+	}
+
+	// 冷却结束后允许试探，试探失败重新熔断
+	time.Sleep(60 * time.Millisecond)
+	if state() != BreakerHalfOpen {
+		t.Fatalf("state after cooldown = %s, want half-open", state())
+	}
+
+	breaker.Crawl(market.America{}, aapl, time.Now())
+	if state() != BreakerOpen || calls != 3 {
+		t.Fatalf("state after a failed probe = %s with %d calls, want open after 3 calls", state(), calls)
+	}
+
+	// 试探成功后恢复正常
+	time.Sleep(60 * time.Millisecond)
+	failing = false
+	if _, err := breaker.Crawl(market.America{}, aapl, time.Now()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+
+	if state() != BreakerClosed {
+		t.Errorf("state after a successful probe = %s, want closed", state())
+	}
+}
+
+func TestBreakerStateString(t *testing.T) {
+
+	for state, expected := range map[BreakerState]string{BreakerClosed: "closed", BreakerOpen: "open", BreakerHalfOpen: "half-open"} {
+		if state.String() != expected {
+			t.Errorf("%d.String() = %s, want %s", state, state.String(), expected)
+		}
+	}
+}
+
+func TestCircuitBreakerFutureDate(t *testing.T) {
+
+	var calls int
+	breaker := NewCircuitBreaker(stubSource{err: ErrFutureDate, calls: &calls}, 2, time.Minute)
+	aapl := market.Company{Code: "AAPL"}
+
+	// 日期还没有开始不算失败，不会熔断
+	for index := 0; index < 3; index++ {
+		if _, err := breaker.Crawl(market.America{}, aapl, time.Now()); err != ErrFutureDate {
+			t.Errorf("Crawl %d = %v, want ErrFutureDate", index, err)
+		}
+	}
+
+	if state := breaker.State(market.America{}, aapl); state != BreakerClosed || calls != 3 {
+		t.Errorf("state after future dates = %s with %d calls, want closed with 3", state, calls)
+	}
+}
@@ -0,0 +1,39 @@
+package source
+
+import (
+	"sync/atomic"
+)
+
+// byteBudget 一次运行的下载字节数限制，可以并发调用，为nil时不限制
+type byteBudget struct {
+	limit int64
+	spent int64
+}
+
+// newByteBudget 新建下载字节数限制，limit不大于0时不限制
+func newByteBudget(limit int64) *byteBudget {
+
+	if limit <= 0 {
+		return nil
+	}
+
+	return &byteBudget{limit: limit}
+}
+
+// spend 累加下载的字节数
+func (b *byteBudget) spend(size int) {
+	if b != nil {
+		atomic.AddInt64(&b.spent, int64(size))
+	}
+}
+
+// exceeded 是否已用完
+func (b *byteBudget) exceeded() bool {
+	return b != nil && atomic.LoadInt64(&b.spent) >= b.limit
+}
+
+// ForRun 返回使用独立下载字节数限制的数据源，供一次运行使用，其余状态与原数据源共享
+func (yahoo YahooFinance) ForRun() Source {
+	yahoo.budget = newByteBudget(yahoo.config.ByteBudget)
+	return yahoo
+}
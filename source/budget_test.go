@@ -0,0 +1,51 @@
+package source
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestByteBudget(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+
+	var hits int32
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{ByteBudget: int64(len(buffer)) + 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write(buffer)
+	}))
+	defer server.Close()
+
+	date := fixtureDate(t)
+	companies := []market.Company{{Code: "AAPL"}, {Code: "MSFT"}, {Code: "GOOG"}}
+
+	// 第一次下载后还差1个字节，第二次下载后用完
+	run := ForRun(yahoo)
+	for index, company := range companies {
+
+		_, err := run.Crawl(market.America{}, company, date)
+		if index < 2 && err != nil {
+			t.Errorf("Crawl %s: %v", company.Code, err)
+		}
+
+		if index == 2 && err != ErrByteBudgetExceeded {
+			t.Errorf("Crawl %s after the budget = %v, want ErrByteBudgetExceeded", company.Code, err)
+		}
+	}
+
+	if hits != 2 {
+		t.Errorf("server got %d requests, want 2", hits)
+	}
+
+	// 下一次运行重新计算
+	if _, err := ForRun(yahoo).Crawl(market.America{}, companies[2], date); err != nil {
+		t.Errorf("Crawl in a new run: %v", err)
+	}
+
+	if stats := yahoo.Stats(); stats.Downloads != 3 || stats.Bytes != int64(3*len(buffer)) {
+		t.Errorf("stats = %+v, want 3 downloads of %d bytes", stats, 3*len(buffer))
+	}
+}
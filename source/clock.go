@@ -0,0 +1,76 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxClockSkew 本机时钟与雅虎服务器相差超过此值时报警，抓取的起止时间可能落到错误的日期
+	maxClockSkew = time.Minute
+	// clockSkewURL 用于获取雅虎服务器时间的网址
+	clockSkewURL = "https://finance-yql.media.yahoo.com/v7/finance/chart/%5EGSPC?range=1d&interval=1d&corsDomain=finance.yahoo.com"
+)
+
+// CheckClockSkew 按雅虎响应的Date头估算本机时钟与雅虎服务器的偏差，本机时钟快时为正
+func (yahoo YahooFinance) CheckClockSkew() (time.Duration, error) {
+
+	client := yahoo.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// 与下载一样限制请求时间，以免启动时卡在校时上
+	ctx := context.Background()
+	if yahoo.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, yahoo.config.Timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequest("GET", clockSkewURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	request = request.WithContext(ctx)
+
+	sent := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	received := time.Now()
+
+	skew, err := clockSkew(response.Header.Get("Date"), sent, received)
+	if err != nil {
+		return 0, err
+	}
+
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		log.Printf("本机时钟与雅虎服务器相差%s，请校准时钟", skew.String())
+	}
+
+	return skew, nil
+}
+
+// clockSkew 以请求发出和收到响应的中间时刻作为本机时间，与服务器Date头比较
+// Date头只精确到秒，误差在1秒左右
+func clockSkew(date string, sent, received time.Time) (time.Duration, error) {
+
+	if date == "" {
+		return 0, fmt.Errorf("雅虎响应中没有Date头")
+	}
+
+	server, err := http.ParseTime(date)
+	if err != nil {
+		return 0, err
+	}
+
+	local := sent.Add(received.Sub(sent) / 2)
+
+	return local.Sub(server).Truncate(time.Second), nil
+}
@@ -0,0 +1,78 @@
+package source
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+
+	sent := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	received := sent.Add(2 * time.Second)
+
+	tests := []struct {
+		date     string
+		expected time.Duration
+	}{
+		// 本机时间取中间时刻12:00:01，本机比服务器快时为正
+		{"Thu, 01 Jun 2017 12:00:01 GMT", 0},
+		{"Thu, 01 Jun 2017 11:58:01 GMT", 2 * time.Minute},
+		{"Thu, 01 Jun 2017 12:00:31 GMT", -30 * time.Second},
+	}
+
+	for _, test := range tests {
+		skew, err := clockSkew(test.date, sent, received)
+		if err != nil {
+			t.Errorf("clockSkew(%q): %v", test.date, err)
+			continue
+		}
+
+		if skew != test.expected {
+			t.Errorf("clockSkew(%q) = %s, want %s", test.date, skew, test.expected)
+		}
+	}
+
+	for _, date := range []string{"", "yesterday"} {
+		if _, err := clockSkew(date, sent, received); err == nil {
+			t.Errorf("clockSkew(%q) should fail", date)
+		}
+	}
+}
+
+func TestCheckClockSkew(t *testing.T) {
+
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	skew, err := yahoo.CheckClockSkew()
+	if err != nil {
+		t.Fatalf("CheckClockSkew: %v", err)
+	}
+
+	// 服务器快1小时
+	if skew > -time.Hour+2*time.Second || skew < -time.Hour-2*time.Second {
+		t.Errorf("CheckClockSkew = %s, want about -1h", skew)
+	}
+}
+
+func TestCheckClockSkewTimeout(t *testing.T) {
+
+	release := make(chan struct{})
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{Timeout: 50 * time.Millisecond}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	if _, err := yahoo.CheckClockSkew(); err == nil {
+		t.Fatal("CheckClockSkew against a hanging server should fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CheckClockSkew took %s, want it bounded by the 50ms timeout", elapsed)
+	}
+}
@@ -0,0 +1,75 @@
+package source
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// errDownloadPanicked 合并的下载发生了panic
+	errDownloadPanicked = errors.New("合并的下载发生了panic")
+)
+
+// downloadCall 一次下载，相同网址的并发请求共享结果
+type downloadCall struct {
+	wg      sync.WaitGroup
+	buffer  []byte
+	err     error
+	expires time.Time // 成功后结果的过期时间
+}
+
+// downloadGroup 合并相同网址的下载
+type downloadGroup struct {
+	mutex sync.Mutex
+	calls map[string]*downloadCall
+	ttl   time.Duration // 成功结果的保留时间，为0时只合并正在进行的下载
+}
+
+// newDownloadGroup 新建下载合并
+func newDownloadGroup(ttl time.Duration) *downloadGroup {
+	return &downloadGroup{calls: make(map[string]*downloadCall), ttl: ttl}
+}
+
+// Do 下载网址，已有相同网址正在下载或结果未过期时直接使用其结果，并返回是否使用了其他下载的结果
+// retain为false时只合并正在进行的下载，成功的结果也不保留，用于实时数据
+func (g *downloadGroup) Do(url string, retain bool, fetch func(string) ([]byte, error)) ([]byte, bool, error) {
+
+	now := time.Now()
+
+	g.mutex.Lock()
+	if call, found := g.calls[url]; found && (call.expires.IsZero() || now.Before(call.expires)) {
+		g.mutex.Unlock()
+		call.wg.Wait()
+		return call.buffer, true, call.err
+	}
+
+	// 清除过期的结果
+	for key, call := range g.calls {
+		if !call.expires.IsZero() && !now.Before(call.expires) {
+			delete(g.calls, key)
+		}
+	}
+
+	call := &downloadCall{err: errDownloadPanicked}
+	call.wg.Add(1)
+	g.calls[url] = call
+	g.mutex.Unlock()
+
+	// fetch发生panic时等待的调用者也能返回，得到errDownloadPanicked
+	defer call.wg.Done()
+	defer func() {
+		g.mutex.Lock()
+		if call.err != nil || !retain || g.ttl <= 0 {
+			// 失败的结果不保留
+			delete(g.calls, url)
+		} else {
+			call.expires = time.Now().Add(g.ttl)
+		}
+		g.mutex.Unlock()
+	}()
+
+	call.buffer, call.err = fetch(url)
+
+	return call.buffer, false, call.err
+}
@@ -0,0 +1,205 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestCoalesceConcurrentDownloads(t *testing.T) {
+
+	attempts := new(attemptLog)
+	release := make(chan struct{})
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.add()
+		<-release
+		w.Write([]byte("chart"))
+	}))
+	defer server.Close()
+
+	const callers = 5
+	buffers := make([][]byte, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	for index := 0; index < callers; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			buffers[index], errs[index] = yahoo.download(context.Background(), server.URL+"/v7/finance/chart/AAPL")
+		}(index)
+	}
+
+	// 所有调用都开始后再返回第一个请求
+	deadline := time.Now().Add(5 * time.Second)
+	for yahoo.Stats().Downloads < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if attempts.count() != 1 {
+		t.Errorf("server got %d requests for %d identical downloads, want 1", attempts.count(), callers)
+	}
+
+	for index := range buffers {
+		if errs[index] != nil || string(buffers[index]) != "chart" {
+			t.Errorf("caller %d got %q, %v, want the shared result", index, buffers[index], errs[index])
+		}
+	}
+
+	stats := yahoo.Stats()
+	if stats.Downloads != callers || stats.Requests != 1 || stats.Coalesced != callers-1 {
+		t.Errorf("stats = %+v, want %d downloads, 1 request, %d coalesced", stats, callers, callers-1)
+	}
+}
+
+func TestCoalesceTTL(t *testing.T) {
+
+	attempts := new(attemptLog)
+	var fail int32
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1, DedupeTTL: 100 * time.Millisecond}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.add()
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("chart"))
+	}))
+	defer server.Close()
+
+	download := func(path string) error {
+		_, err := yahoo.downloadDay(context.Background(), server.URL+path)
+		return err
+	}
+
+	// 保留期内重复下载使用之前的结果
+	download("/ok")
+	download("/ok")
+	if attempts.count() != 1 {
+		t.Errorf("server got %d requests within the TTL, want 1", attempts.count())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	download("/ok")
+	if attempts.count() != 2 {
+		t.Errorf("server got %d requests after the TTL, want 2", attempts.count())
+	}
+
+	// 失败的结果不保留
+	atomic.StoreInt32(&fail, 1)
+	if download("/failed") == nil {
+		t.Fatal("download of an unavailable page should fail")
+	}
+	download("/failed")
+	if attempts.count() != 4 {
+		t.Errorf("server got %d requests, want failed downloads retried on the next call", attempts.count())
+	}
+}
+
+func TestCoalesceWithoutTTL(t *testing.T) {
+
+	attempts := new(attemptLog)
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.add()
+		w.Write([]byte("chart"))
+	}))
+	defer server.Close()
+
+	// 未设置保留时间时只合并同时进行的下载
+	for index := 0; index < 2; index++ {
+		if _, err := yahoo.download(context.Background(), server.URL+"/ok"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if attempts.count() != 2 {
+		t.Errorf("server got %d requests for sequential downloads, want 2", attempts.count())
+	}
+}
+
+func TestCoalesceTTLSkipsSnapshot(t *testing.T) {
+
+	attempts := new(attemptLog)
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1, DedupeTTL: time.Minute}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.add()
+		if strings.HasSuffix(r.URL.Path, "/quote") {
+			w.Write(readFixture(t, "quote_batch.json"))
+			return
+		}
+		w.Write(readFixture(t, "chart_1m.json"))
+	}))
+	defer server.Close()
+
+	// 实时报价每次都重新请求
+	for index := 0; index < 2; index++ {
+		if _, err := yahoo.Snapshot([]string{"AAPL", "0700.HK"}); err != nil {
+			t.Fatalf("Snapshot: %v", err)
+		}
+	}
+
+	if attempts.count() != 2 {
+		t.Errorf("server got %d requests for 2 snapshots, want 2", attempts.count())
+	}
+
+	// 历史某一天的分时数据在保留期内重复使用
+	for index := 0; index < 2; index++ {
+		if _, err := yahoo.Crawl(market.America{}, market.Company{Code: "AAPL"}, fixtureDate(t)); err != nil {
+			t.Fatalf("Crawl: %v", err)
+		}
+	}
+
+	if attempts.count() != 3 {
+		t.Errorf("server got %d requests after 2 crawls of the same day, want 3", attempts.count())
+	}
+}
+
+func TestCoalescePanic(t *testing.T) {
+
+	group := newDownloadGroup(time.Minute)
+	release := make(chan struct{})
+
+	// 第一个下载在有调用者等待时发生panic
+	go func() {
+		defer func() { recover() }()
+		group.Do("/chart", true, func(string) ([]byte, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, coalesced, err := group.Do("/chart", true, func(string) ([]byte, error) { return []byte("second"), nil })
+		if !coalesced {
+			err = errors.New("waiter was not coalesced")
+		}
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != errDownloadPanicked {
+			t.Errorf("waiter got %v, want errDownloadPanicked", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter blocked after the download panicked")
+	}
+
+	// panic的结果不保留
+	buffer, coalesced, err := group.Do("/chart", true, func(string) ([]byte, error) { return []byte("retry"), nil })
+	if err != nil || coalesced || string(buffer) != "retry" {
+		t.Errorf("Do after the panic = %q, %v, %v, want a fresh download", buffer, coalesced, err)
+	}
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// CrawlDailyBars 获取公司在[from, to)之间的日线报价(只含正常交易时段)，每个交易日一条
+func (yahoo YahooFinance) CrawlDailyBars(_market market.Market, company market.Company, from, to time.Time) (*market.QuoteSeries, error) {
+
+	queryCode, err := yahoo.queryCode(_market, company)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s的雅虎查询代码时发生错误: %v", company.Code, err)
+	}
+
+	pattern := "https://finance-yql.media.yahoo.com/v7/finance/chart/%s?period2=%d&period1=%d&interval=1d&indicators=quote&includeTimestamps=true&includePrePost=false&corsDomain=finance.yahoo.com"
+	url := fmt.Sprintf(pattern, queryCode, to.Unix(), from.Unix())
+
+	buffer, err := yahoo.download(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+
+	return yahoo.ParseDailyBars(_market, buffer)
+}
+
+// ParseDailyBars 解析日线报价json，成交量与分时报价一样按市场的成交量倍数换算
+func (yahoo YahooFinance) ParseDailyBars(_market market.Market, buffer []byte) (*market.QuoteSeries, error) {
+
+	quote := &YahooQuote{}
+	err := yahoo.decode(buffer, quote)
+	if err != nil {
+		return nil, err
+	}
+	quote.NormalizeTimestamps()
+	quote.FillMissingVolume()
+
+	// 日线没有TradingPeriods，只校验报价
+	if quote.Chart.Err != nil {
+		return nil, errors.New(quote.Chart.Err.Description)
+	}
+
+	if len(quote.Chart.Result) == 0 || len(quote.Chart.Result[0].Indicators.Quotes) == 0 {
+		return nil, errors.New("quote.Chart.Result[0].Indicators.Quotes is null")
+	}
+
+	timestamps, _quote := quote.Chart.Result[0].Timestamp, quote.Chart.Result[0].Indicators.Quotes[0]
+	if len(timestamps) != len(_quote.Open) ||
+		len(timestamps) != len(_quote.Close) ||
+		len(timestamps) != len(_quote.High) ||
+		len(timestamps) != len(_quote.Low) ||
+		len(timestamps) != len(_quote.Volume) {
+		return nil, errors.New("Quotes数量不正确")
+	}
+
+	multiplier := yahoo.volumeMultiplier(_market)
+	series := &market.QuoteSeries{}
+	for index, ts := range timestamps {
+
+		//	如果全为0就忽略
+		if _quote.Open[index] == 0 && _quote.Close[index] == 0 && _quote.High[index] == 0 && _quote.Low[index] == 0 && _quote.Volume[index] == 0 {
+			continue
+		}
+
+		series.Count++
+		series.Timestamp = append(series.Timestamp, uint32(ts))
+		series.Open = append(series.Open, uint32(_quote.Open[index]*100))
+		series.Close = append(series.Close, uint32(_quote.Close[index]*100))
+		series.Max = append(series.Max, uint32(_quote.High[index]*100))
+		series.Min = append(series.Min, uint32(_quote.Low[index]*100))
+		series.Volume = append(series.Volume, uint32(_quote.Volume[index])*multiplier)
+	}
+
+	return series, nil
+}
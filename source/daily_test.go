@@ -0,0 +1,82 @@
+package source
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestParseDailyBars(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1d.json")
+
+	tests := []struct {
+		multipliers map[string]uint32
+		volumes     []uint32
+	}{
+		{nil, []uint32{16404088, 27770715}},
+		{map[string]uint32{"america": 100}, []uint32{1640408800, 2777071500}},
+		// 其他市场的倍数不影响
+		{map[string]uint32{"China": 100}, []uint32{16404088, 27770715}},
+	}
+
+	for _, test := range tests {
+
+		yahoo := NewYahooFinance(YahooFinanceConfig{VolumeMultipliers: test.multipliers})
+		series, err := yahoo.ParseDailyBars(market.America{}, buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if series.Count != 2 {
+			t.Fatalf("Count = %d, want 2", series.Count)
+		}
+
+		if series.Timestamp[0] != 1496323800 || series.Open[0] != 15317 || series.Close[1] != 15545 {
+			t.Errorf("bars = %+v, %+v, want timestamp 1496323800, open 15317 and second close 15545", series.Bar(0), series.Bar(1))
+		}
+
+		for index, volume := range test.volumes {
+			if series.Volume[index] != volume {
+				t.Errorf("multipliers %v: Volume[%d] = %d, want %d", test.multipliers, index, series.Volume[index], volume)
+			}
+		}
+	}
+}
+
+func TestCrawlDailyBars(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1d.json")
+
+	var path string
+	var query url.Values
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		w.Write(buffer)
+	}))
+	defer server.Close()
+
+	from := fixtureDate(t)
+	to := from.AddDate(0, 0, 2)
+
+	series, err := yahoo.CrawlDailyBars(market.America{}, market.Company{Code: "aapl"}, from, to)
+	if err != nil {
+		t.Fatalf("CrawlDailyBars: %v", err)
+	}
+
+	// 一次请求取回整个区间的日线，只含正常交易时段
+	if path != "/v7/finance/chart/AAPL" || query.Get("interval") != "1d" || query.Get("includePrePost") != "false" {
+		t.Errorf("requested %s?%s, want daily regular-session bars of AAPL", path, query.Encode())
+	}
+
+	if query.Get("period1") != strconv.FormatInt(from.Unix(), 10) || query.Get("period2") != strconv.FormatInt(to.Unix(), 10) {
+		t.Errorf("period = [%s, %s), want [%d, %d)", query.Get("period1"), query.Get("period2"), from.Unix(), to.Unix())
+	}
+
+	if series.Count != 2 {
+		t.Errorf("Count = %d, want 2", series.Count)
+	}
+}
@@ -0,0 +1,164 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// download 访问网址并返回内容，相同网址的并发请求只访问一次，结果不保留
+// 合并的请求使用第一个调用者的ctx
+func (yahoo YahooFinance) download(ctx context.Context, url string) ([]byte, error) {
+	return yahoo.downloadShared(ctx, url, false)
+}
+
+// downloadDay 下载某一天的历史数据，除合并并发请求外，成功的结果在DedupeTTL内重复使用
+func (yahoo YahooFinance) downloadDay(ctx context.Context, url string) ([]byte, error) {
+	return yahoo.downloadShared(ctx, url, true)
+}
+
+// downloadShared 访问网址并返回内容，retain为true时保留成功的结果
+func (yahoo YahooFinance) downloadShared(ctx context.Context, url string, retain bool) (buffer []byte, err error) {
+
+	ctx, endSpan := startSpan(ctx, yahoo.tracer, "YahooFinance.Download", map[string]string{"url": url})
+	defer func() { endSpan(err) }()
+
+	// 超过本次运行的字节数限制后不再下载，已开始的下载不受影响
+	if yahoo.budget.exceeded() {
+		return nil, ErrByteBudgetExceeded
+	}
+
+	yahoo.counters.countDownload()
+
+	fetch := func(url string) ([]byte, error) {
+		return yahoo.downloadWithRetry(ctx, url)
+	}
+
+	if yahoo.downloads == nil {
+		return fetch(url)
+	}
+
+	buffer, coalesced, err := yahoo.downloads.Do(url, retain, fetch)
+	if coalesced {
+		yahoo.counters.countCoalesced()
+	}
+
+	return buffer, err
+}
+
+// downloadWithRetry 访问网址并返回内容，失败时按配置重试
+func (yahoo YahooFinance) downloadWithRetry(ctx context.Context, url string) ([]byte, error) {
+
+	retryCount := yahoo.RetryCount()
+	if retryCount < 1 {
+		retryCount = 1
+	}
+
+	var err error
+	for times := retryCount - 1; times >= 0; times-- {
+
+		var buffer []byte
+		var retryAfter time.Duration
+		buffer, retryAfter, err = yahoo.downloadOnce(ctx, url, retryCount-times)
+		if err == nil {
+			return buffer, nil
+		}
+
+		if times > 0 {
+			// 服务器要求的等待时间优先
+			interval := yahoo.RetryInterval()
+			if retryAfter > interval {
+				interval = retryAfter
+			}
+
+			log.Printf("访问%s出错，还有%d次重试机会，%d秒后重试:%s", url, times, int64(interval.Seconds()), err.Error())
+
+			//	延时，ctx取消后不再重试
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				yahoo.counters.countFailure()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	yahoo.counters.countFailure()
+
+	return nil, fmt.Errorf("访问%s出错，已重试%d次，不再重试:%s", url, retryCount, err.Error())
+}
+
+// downloadOnce 访问网址并返回内容，被限流时同时返回服务器要求的等待时间
+func (yahoo YahooFinance) downloadOnce(ctx context.Context, url string, attempt int) (buffer []byte, retryAfter time.Duration, err error) {
+
+	ctx, endSpan := startSpan(ctx, yahoo.tracer, "YahooFinance.Request", map[string]string{
+		"url":     url,
+		"attempt": strconv.Itoa(attempt),
+	})
+	defer func() { endSpan(err) }()
+
+	client := yahoo.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// 每次请求单独计时，连接挂起时按失败重试而不是一直阻塞
+	if yahoo.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, yahoo.config.Timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	request = request.WithContext(ctx)
+
+	yahoo.counters.countRequest()
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+
+	// 限流或服务暂不可用
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		return nil, parseRetryAfter(response.Header.Get("Retry-After"), time.Now()), fmt.Errorf("服务器返回%s", response.Status)
+	}
+
+	buffer, err = ioutil.ReadAll(response.Body)
+	yahoo.counters.countBytes(len(buffer))
+	yahoo.budget.spend(len(buffer))
+
+	return buffer, 0, err
+}
+
+// parseRetryAfter 解析Retry-After，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string, now time.Time) time.Duration {
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil || date.Before(now) {
+		return 0
+	}
+
+	return date.Sub(now)
+}
@@ -0,0 +1,126 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// attemptLog 记录测试服务器收到每次请求的时间
+type attemptLog struct {
+	mutex sync.Mutex
+	times []time.Time
+}
+
+// add 记录一次请求
+func (l *attemptLog) add() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.times = append(l.times, time.Now())
+	return len(l.times)
+}
+
+// count 请求次数
+func (l *attemptLog) count() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.times)
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	attempts := new(attemptLog)
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 2, RetryInterval: 10 * time.Millisecond}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.add() == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	buffer, err := yahoo.download(context.Background(), server.URL+"/throttled")
+	if err != nil || string(buffer) != "ok" {
+		t.Fatalf("download = %q, %v, want ok after one retry", buffer, err)
+	}
+
+	if attempts.count() != 2 {
+		t.Fatalf("server got %d requests, want 2", attempts.count())
+	}
+
+	// 服务器要求的2秒比配置的重试间隔长，按服务器的等待
+	if wait := attempts.times[1].Sub(attempts.times[0]); wait < 2*time.Second {
+		t.Errorf("retried after %s, want at least the 2s from Retry-After", wait)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+
+	attempts := new(attemptLog)
+	aborted := make(chan struct{}, 1)
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 2, RetryInterval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.add() == 1 {
+			// 第一次请求挂起，直到客户端放弃
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+				aborted <- struct{}{}
+			}
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	buffer, err := yahoo.download(context.Background(), server.URL+"/hung")
+	if err != nil || string(buffer) != "ok" {
+		t.Fatalf("download = %q, %v, want ok after the hung attempt is retried", buffer, err)
+	}
+
+	if attempts.count() != 2 {
+		t.Errorf("server got %d requests, want 2", attempts.count())
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("download took %s, want the hung attempt cut off after 50ms", elapsed)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("hung request was not aborted")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"2", 2 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"-1", 0},
+		{"Thu, 01 Jun 2017 12:00:30 GMT", 30 * time.Second},
+		// 已经过去的时间不等待
+		{"Thu, 01 Jun 2017 11:59:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, test := range tests {
+		if wait := parseRetryAfter(test.value, now); wait != test.expected {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", test.value, wait, test.expected)
+		}
+	}
+}
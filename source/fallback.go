@@ -0,0 +1,109 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+var (
+	// ErrNoSource 没有可用的数据源
+	ErrNoSource = errors.New("没有可用的数据源")
+)
+
+// Fallback 依次尝试多个数据源，直到某个数据源成功
+type Fallback struct {
+	sources []Source
+}
+
+// FallbackChain 新建依次尝试的数据源，第一个为主数据源
+func FallbackChain(sources ...Source) Fallback {
+	return Fallback{sources: sources}
+}
+
+// ForRun 各数据源分别换成本次运行使用的数据源
+func (f Fallback) ForRun() Source {
+
+	sources := make([]Source, 0, len(f.sources))
+	for _, source := range f.sources {
+		sources = append(sources, ForRun(source))
+	}
+
+	return Fallback{sources: sources}
+}
+
+// Expiration 以主数据源为准
+func (f Fallback) Expiration() time.Duration {
+
+	if len(f.sources) == 0 {
+		return 0
+	}
+
+	return f.sources[0].Expiration()
+}
+
+// Crawl 依次从各数据源获取公司每天的报价
+func (f Fallback) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	if len(f.sources) == 0 {
+		return nil, ErrNoSource
+	}
+
+	var messages []string
+	for index, source := range f.sources {
+
+		quote, err := source.Crawl(_market, company, date)
+		if err == nil {
+			return quote, nil
+		}
+
+		// 日期还没有开始时其他数据源同样没有报价
+		if err == ErrFutureDate {
+			return nil, err
+		}
+
+		messages = append(messages, fmt.Sprintf("[%d] %v", index, err))
+	}
+
+	return nil, fmt.Errorf("所有数据源均获取失败: %s", strings.Join(messages, "; "))
+}
+
+// ParallelMax 取各数据源中最小的并发数，至少为1，没有数据源时抓取直接返回ErrNoSource
+func (f Fallback) ParallelMax(_market market.Market) int {
+
+	parallel := 0
+	for _, source := range f.sources {
+		if parallel == 0 || source.ParallelMax(_market) < parallel {
+			parallel = source.ParallelMax(_market)
+		}
+	}
+
+	if parallel < 1 {
+		return 1
+	}
+
+	return parallel
+}
+
+// RetryCount 以主数据源为准
+func (f Fallback) RetryCount() int {
+
+	if len(f.sources) == 0 {
+		return 0
+	}
+
+	return f.sources[0].RetryCount()
+}
+
+// RetryInterval 以主数据源为准
+func (f Fallback) RetryInterval() time.Duration {
+
+	if len(f.sources) == 0 {
+		return 0
+	}
+
+	return f.sources[0].RetryInterval()
+}
@@ -0,0 +1,114 @@
+package source
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// stubSource 返回固定结果的数据源，记录调用次数
+type stubSource struct {
+	err      error
+	parallel int
+	calls    *int
+}
+
+func (s stubSource) Expiration() time.Duration { return time.Hour * 24 }
+
+func (s stubSource) ParallelMax(_market market.Market) int { return s.parallel }
+
+func (s stubSource) RetryCount() int { return s.parallel }
+
+func (s stubSource) RetryInterval() time.Duration { return time.Duration(s.parallel) * time.Second }
+
+// Crawl 返回固定结果
+func (s stubSource) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	if s.calls != nil {
+		*s.calls++
+	}
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &market.CompanyDailyQuote{Company: company}, nil
+}
+
+func TestFallback(t *testing.T) {
+
+	var primaryCalls, secondaryCalls int
+	broken := stubSource{err: errors.New("primary down"), parallel: 4, calls: &primaryCalls}
+	healthy := stubSource{parallel: 2, calls: &secondaryCalls}
+	company := market.Company{Code: "AAPL"}
+
+	// 主数据源失败时使用备用数据源
+	chain := FallbackChain(broken, healthy)
+	quote, err := chain.Crawl(market.America{}, company, time.Now())
+	if err != nil || quote.Code != "AAPL" {
+		t.Errorf("Crawl = %v, %v, want the secondary's quote", quote, err)
+	}
+
+	if primaryCalls != 1 || secondaryCalls != 1 {
+		t.Errorf("calls = %d/%d, want 1/1", primaryCalls, secondaryCalls)
+	}
+
+	// 主数据源成功时不访问备用数据源
+	FallbackChain(healthy, broken).Crawl(market.America{}, company, time.Now())
+	if primaryCalls != 1 || secondaryCalls != 2 {
+		t.Errorf("calls = %d/%d, want 1/2", primaryCalls, secondaryCalls)
+	}
+
+	// 并发数取最小，重试以主数据源为准
+	if chain.ParallelMax(market.America{}) != 2 || chain.RetryCount() != 4 || chain.RetryInterval() != 4*time.Second {
+		t.Errorf("ParallelMax/RetryCount/RetryInterval = %d/%d/%s, want 2/4/4s", chain.ParallelMax(market.America{}), chain.RetryCount(), chain.RetryInterval())
+	}
+
+	// 全部失败时返回所有错误
+	_, err = FallbackChain(broken, stubSource{err: errors.New("secondary down")}).Crawl(market.America{}, company, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "primary down") || !strings.Contains(err.Error(), "secondary down") {
+		t.Errorf("Crawl with every source down: err = %v, want both errors", err)
+	}
+
+	if _, err = FallbackChain().Crawl(market.America{}, company, time.Now()); err != ErrNoSource {
+		t.Errorf("Crawl of an empty chain: err = %v, want ErrNoSource", err)
+	}
+}
+
+func TestFallbackParallelMaxAtLeastOne(t *testing.T) {
+
+	// 并发数为0时记录器的信号量没有缓冲，抓取会一直阻塞
+	tests := []struct {
+		name  string
+		chain Fallback
+	}{
+		{"empty chain", FallbackChain()},
+		{"zero parallel", FallbackChain(stubSource{}, stubSource{})},
+	}
+
+	for _, test := range tests {
+		if parallel := test.chain.ParallelMax(market.America{}); parallel != 1 {
+			t.Errorf("%s: ParallelMax = %d, want 1", test.name, parallel)
+		}
+	}
+}
+
+func TestFallbackFutureDate(t *testing.T) {
+
+	var primaryCalls, secondaryCalls int
+	future := stubSource{err: ErrFutureDate, calls: &primaryCalls}
+	healthy := stubSource{calls: &secondaryCalls}
+
+	// 日期还没有开始时不再尝试其他数据源，错误原样返回
+	_, err := FallbackChain(future, healthy).Crawl(market.America{}, market.Company{Code: "AAPL"}, time.Now())
+	if err != ErrFutureDate {
+		t.Errorf("Crawl = %v, want ErrFutureDate", err)
+	}
+
+	if primaryCalls != 1 || secondaryCalls != 0 {
+		t.Errorf("calls = %d/%d, want 1/0", primaryCalls, secondaryCalls)
+	}
+}
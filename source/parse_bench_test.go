@@ -0,0 +1,106 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// fullDayFixture 在chart_1m.json的基础上生成盘前60个、正常交易时段390个、盘后60个报价的返回
+func fullDayFixture(tb testing.TB) []byte {
+	tb.Helper()
+
+	const preStart, regularStart, postStart = 1496320200, 1496323800, 1496347200
+
+	var timestamps []int64
+	for minute := int64(0); minute < 60; minute++ {
+		timestamps = append(timestamps, preStart+minute*60)
+	}
+	for minute := int64(0); minute < 390; minute++ {
+		timestamps = append(timestamps, regularStart+minute*60)
+	}
+	for minute := int64(0); minute < 60; minute++ {
+		timestamps = append(timestamps, postStart+minute*60)
+	}
+
+	join := func(value func(index int) string) string {
+		values := make([]string, len(timestamps))
+		for index := range timestamps {
+			values[index] = value(index)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+
+	price := func(index int, delta float64) string {
+		return fmt.Sprintf("%.2f", 150+float64(index%50)/10+delta)
+	}
+
+	indicators := fmt.Sprintf(`"timestamp": %s,
+        "indicators": {
+          "quote": [
+            {
+              "open": %s,
+              "close": %s,
+              "high": %s,
+              "low": %s,
+              "volume": %s
+            }
+          ]
+        }
+      }
+    ],`,
+		join(func(index int) string { return fmt.Sprint(timestamps[index]) }),
+		join(func(index int) string { return price(index, 0) }),
+		join(func(index int) string { return price(index, 0.05) }),
+		join(func(index int) string { return price(index, 0.1) }),
+		join(func(index int) string { return price(index, -0.1) }),
+		join(func(index int) string { return fmt.Sprint(1000 + index) }),
+	)
+
+	buffer := string(readFixture(tb, "chart_1m.json"))
+	start := strings.Index(buffer, `"timestamp":`)
+	end := strings.Index(buffer, `"error":`)
+	if start < 0 || end < 0 {
+		tb.Fatal("unexpected chart_1m.json layout")
+	}
+
+	return []byte(buffer[:start] + indicators + "\n    " + buffer[end:])
+}
+
+func TestParseFullDay(t *testing.T) {
+
+	var yahoo YahooFinance
+	cdq, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL"}, fixtureDate(t), fullDayFixture(t))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if cdq.Pre.Count != 60 || cdq.Regular.Count != 390 || cdq.Post.Count != 60 {
+		t.Errorf("sessions = %d/%d/%d bars, want 60/390/60", cdq.Pre.Count, cdq.Regular.Count, cdq.Post.Count)
+	}
+
+	// 各数组按数量一次性分配，没有多余容量
+	for name, series := range map[string]market.QuoteSeries{"pre": cdq.Pre, "regular": cdq.Regular, "post": cdq.Post} {
+		if cap(series.Volume) != int(series.Count) || cap(series.Timestamp) != int(series.Count) {
+			t.Errorf("%s: capacity %d/%d for %d bars", name, cap(series.Timestamp), cap(series.Volume), series.Count)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+
+	var yahoo YahooFinance
+	buffer := fullDayFixture(b)
+	date := fixtureDate(b)
+	company := market.Company{Code: "AAPL"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		if _, err := yahoo.Parse(market.America{}, company, date, buffer); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
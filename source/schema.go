@@ -0,0 +1,100 @@
+package source
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DetectSchema 检查雅虎财经返回的json与YahooQuote结构的差异，返回未知字段的路径
+// 雅虎调整字段时可以据此报警，避免数据悄悄丢失
+func DetectSchema(buffer []byte) []string {
+
+	var value interface{}
+	err := json.Unmarshal(buffer, &value)
+	if err != nil {
+		return []string{"无法解析的json: " + err.Error()}
+	}
+
+	found := make(map[string]bool)
+	unknownFields("", value, reflect.TypeOf(YahooQuote{}), found)
+
+	// 关键字段缺失
+	quote := &YahooQuote{}
+	if json.Unmarshal(buffer, quote) == nil && quote.Chart.Err == nil && len(quote.Chart.Result) == 0 {
+		found["缺少chart.result"] = true
+	}
+
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// unknownFields 递归比较json值和结构，记录未知字段
+func unknownFields(path string, value interface{}, t reflect.Type, found map[string]bool) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		for key, child := range object {
+			field, ok := jsonField(t, key)
+			if !ok {
+				found[strings.TrimPrefix(path+"."+key, ".")] = true
+				continue
+			}
+
+			unknownFields(path+"."+key, child, field.Type, found)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		// 键为时间戳等数据，只比较值
+		for _, child := range object {
+			unknownFields(path+"{}", child, t.Elem(), found)
+		}
+	case reflect.Slice:
+		array, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+
+		for _, child := range array {
+			unknownFields(path+"[]", child, t.Elem(), found)
+		}
+	}
+}
+
+// jsonField 按json名称查找字段，与encoding/json一样不区分大小写
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+
+	for index := 0; index < t.NumField(); index++ {
+
+		field := t.Field(index)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
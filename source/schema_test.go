@@ -0,0 +1,35 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectSchema(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+
+	tests := []struct {
+		name     string
+		buffer   []byte
+		expected []string
+	}{
+		{"fixture", buffer, []string{}},
+		{"new meta field", patchFixture(t, buffer, `"scale": 3,`, `"scale": 3, "marketState": "CLOSED",`), []string{"chart.result[].meta.marketState"}},
+		{"renamed quote field", patchFixture(t, buffer, `"volume": [`, `"vol": [`), []string{"chart.result[].indicators.quote[].vol"}},
+		{"new top-level field", patchFixture(t, buffer, `"error": null`, `"error": null, "finance": {}`), []string{"chart.finance"}},
+		{"trading period", patchFixture(t, buffer, `"pre": [[{"timezone": "EDT",`, `"pre": [[{"timezone": "EDT", "name": "pre",`), []string{"chart.result[].meta.tradingPeriods.pre[][].name"}},
+		{"missing result", []byte(`{"chart": {"result": [], "error": null}}`), []string{"缺少chart.result"}},
+	}
+
+	for _, test := range tests {
+		found := DetectSchema(test.buffer)
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("%s: DetectSchema = %q, want %q", test.name, found, test.expected)
+		}
+	}
+
+	if found := DetectSchema([]byte(`{"chart":`)); len(found) != 1 {
+		t.Errorf("DetectSchema of truncated json = %q, want one parse error", found)
+	}
+}
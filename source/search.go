@@ -0,0 +1,77 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// SymbolCandidate 雅虎查询代码候选
+type SymbolCandidate struct {
+	QueryCode string // 雅虎查询代码
+	Name      string // 名称
+	Exchange  string // 交易所
+	Type      string // 证券类型
+}
+
+// ResolveSymbol 通过雅虎财经的搜索接口查找股票代码对应的查询代码
+func (yahoo YahooFinance) ResolveSymbol(ticker string) ([]SymbolCandidate, error) {
+
+	pattern := "https://query1.finance.yahoo.com/v1/finance/search?q=%s&quotesCount=20&newsCount=0"
+	buffer, err := yahoo.download(context.Background(), fmt.Sprintf(pattern, url.QueryEscape(ticker)))
+	if err != nil {
+		return nil, err
+	}
+
+	return yahoo.parseSearch(buffer)
+}
+
+// parseSearch 解析搜索结果
+func (yahoo YahooFinance) parseSearch(buffer []byte) ([]SymbolCandidate, error) {
+
+	result := &YahooSearchResult{}
+	err := json.Unmarshal(buffer, result)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []SymbolCandidate
+	for _, quote := range result.Quotes {
+
+		if quote.Symbol == "" {
+			continue
+		}
+
+		name := quote.LongName
+		if name == "" {
+			name = quote.ShortName
+		}
+
+		exchange := quote.ExchangeDisplay
+		if exchange == "" {
+			exchange = quote.Exchange
+		}
+
+		candidates = append(candidates, SymbolCandidate{
+			QueryCode: quote.Symbol,
+			Name:      name,
+			Exchange:  exchange,
+			Type:      quote.QuoteType,
+		})
+	}
+
+	return candidates, nil
+}
+
+// YahooSearchResult 雅虎财经搜索接口返回的json
+type YahooSearchResult struct {
+	Quotes []struct {
+		Symbol          string `json:"symbol"`
+		ShortName       string `json:"shortname"`
+		LongName        string `json:"longname"`
+		Exchange        string `json:"exchange"`
+		ExchangeDisplay string `json:"exchDisp"`
+		QuoteType       string `json:"quoteType"`
+	} `json:"quotes"`
+}
@@ -0,0 +1,56 @@
+package source
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolveSymbol(t *testing.T) {
+
+	buffer := readFixture(t, "search_tencent.json")
+
+	var query string
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		w.Write(buffer)
+	}))
+	defer server.Close()
+
+	candidates, err := yahoo.ResolveSymbol("腾讯 控股")
+	if err != nil {
+		t.Fatalf("ResolveSymbol: %v", err)
+	}
+
+	if query != "腾讯 控股" {
+		t.Errorf("searched for %q, want the ticker unchanged", query)
+	}
+
+	// 没有代码的结果跳过，没有全称时使用简称，没有交易所显示名时使用交易所代码
+	expected := []SymbolCandidate{
+		{QueryCode: "0700.HK", Name: "Tencent Holdings Limited", Exchange: "Hong Kong", Type: "EQUITY"},
+		{QueryCode: "TCEHY", Name: "TENCENT HLDGS LTD", Exchange: "PNK", Type: "EQUITY"},
+	}
+
+	if len(candidates) != len(expected) {
+		t.Fatalf("candidates = %+v, want %+v", candidates, expected)
+	}
+
+	for index := range expected {
+		if candidates[index] != expected[index] {
+			t.Errorf("candidate %d = %+v, want %+v", index, candidates[index], expected[index])
+		}
+	}
+}
+
+func TestParseSearchInvalid(t *testing.T) {
+
+	var yahoo YahooFinance
+	if _, err := yahoo.parseSearch([]byte(`{"quotes": {}}`)); err == nil {
+		t.Error("parseSearch of a malformed response should fail")
+	}
+
+	candidates, err := yahoo.parseSearch([]byte(`{"quotes": []}`))
+	if err != nil || len(candidates) != 0 {
+		t.Errorf("parseSearch of no results = %v, %v, want none", candidates, err)
+	}
+}
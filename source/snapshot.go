@@ -0,0 +1,97 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// snapshotBatchSize 每次请求的最大代码数
+	snapshotBatchSize = 100
+)
+
+// SnapshotQuote 实时报价
+type SnapshotQuote struct {
+	QueryCode     string    // 雅虎查询代码
+	Currency      string    // 货币
+	Price         float32   // 最新价
+	Change        float32   // 涨跌额
+	ChangePercent float32   // 涨跌幅(%)
+	Time          time.Time // 报价时间
+}
+
+// Snapshot 批量获取实时报价，返回以查询代码为键的报价
+func (yahoo YahooFinance) Snapshot(queryCodes []string) (map[string]SnapshotQuote, error) {
+
+	quotes := make(map[string]SnapshotQuote, len(queryCodes))
+	for start := 0; start < len(queryCodes); start += snapshotBatchSize {
+
+		end := start + snapshotBatchSize
+		if end > len(queryCodes) {
+			end = len(queryCodes)
+		}
+
+		pattern := "https://query1.finance.yahoo.com/v7/finance/quote?symbols=%s"
+		buffer, err := yahoo.download(context.Background(), fmt.Sprintf(pattern, url.QueryEscape(strings.Join(queryCodes[start:end], ","))))
+		if err != nil {
+			return nil, err
+		}
+
+		err = yahoo.parseSnapshot(buffer, quotes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return quotes, nil
+}
+
+// parseSnapshot 解析实时报价
+func (yahoo YahooFinance) parseSnapshot(buffer []byte, quotes map[string]SnapshotQuote) error {
+
+	response := &YahooSnapshotResult{}
+	err := json.Unmarshal(buffer, response)
+	if err != nil {
+		return err
+	}
+
+	if response.QuoteResponse.Err != nil {
+		return errors.New(response.QuoteResponse.Err.Description)
+	}
+
+	for _, quote := range response.QuoteResponse.Result {
+		quotes[quote.Symbol] = SnapshotQuote{
+			QueryCode:     quote.Symbol,
+			Currency:      quote.Currency,
+			Price:         float32(quote.RegularMarketPrice),
+			Change:        float32(quote.RegularMarketChange),
+			ChangePercent: float32(quote.RegularMarketChangePercent),
+			Time:          time.Unix(quote.RegularMarketTime, 0),
+		}
+	}
+
+	return nil
+}
+
+// YahooSnapshotResult 雅虎财经实时报价接口返回的json
+type YahooSnapshotResult struct {
+	QuoteResponse struct {
+		Result []struct {
+			Symbol                     string     `json:"symbol"`
+			Currency                   string     `json:"currency"`
+			RegularMarketPrice         YahooFloat `json:"regularMarketPrice"`
+			RegularMarketChange        YahooFloat `json:"regularMarketChange"`
+			RegularMarketChangePercent YahooFloat `json:"regularMarketChangePercent"`
+			RegularMarketTime          int64      `json:"regularMarketTime"`
+		} `json:"result"`
+		Err *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"quoteResponse"`
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+
+	buffer := readFixture(t, "quote_batch.json")
+
+	var symbols string
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		symbols = r.URL.Query().Get("symbols")
+		w.Write(buffer)
+	}))
+	defer server.Close()
+
+	quotes, err := yahoo.Snapshot([]string{"AAPL", "0700.HK"})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	if symbols != "AAPL,0700.HK" {
+		t.Errorf("requested symbols %q, want AAPL,0700.HK", symbols)
+	}
+
+	tests := []struct {
+		queryCode string
+		currency  string
+		price     float32
+		change    float32
+		unix      int64
+	}{
+		{"AAPL", "USD", 153.18, 0.42, 1496347200},
+		// 价格以字符串返回
+		{"0700.HK", "HKD", 279.6, -1.2, 1496304000},
+	}
+
+	if len(quotes) != len(tests) {
+		t.Fatalf("quotes = %+v, want %d symbols", quotes, len(tests))
+	}
+
+	for _, test := range tests {
+
+		quote, found := quotes[test.queryCode]
+		if !found {
+			t.Errorf("missing quote for %s", test.queryCode)
+			continue
+		}
+
+		if quote.QueryCode != test.queryCode || quote.Currency != test.currency || quote.Price != test.price || quote.Change != test.change || quote.Time.Unix() != test.unix {
+			t.Errorf("quote %s = %+v, want %s %v %v at %d", test.queryCode, quote, test.currency, test.price, test.change, test.unix)
+		}
+	}
+}
+
+func TestSnapshotBatches(t *testing.T) {
+
+	var mutex sync.Mutex
+	var batches []int
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		batches = append(batches, len(strings.Split(r.URL.Query().Get("symbols"), ",")))
+		mutex.Unlock()
+		w.Write([]byte(`{"quoteResponse": {"result": [], "error": null}}`))
+	}))
+	defer server.Close()
+
+	queryCodes := make([]string, snapshotBatchSize+20)
+	for index := range queryCodes {
+		queryCodes[index] = fmt.Sprintf("C%03d", index)
+	}
+
+	if _, err := yahoo.Snapshot(queryCodes); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	if len(batches) != 2 || batches[0] != snapshotBatchSize || batches[1] != 20 {
+		t.Errorf("batch sizes = %v, want [%d 20]", batches, snapshotBatchSize)
+	}
+}
+
+func TestSnapshotError(t *testing.T) {
+
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"quoteResponse": {"result": null, "error": {"code": "Bad Request", "description": "Missing value for the \"symbols\" argument"}}}`))
+	}))
+	defer server.Close()
+
+	_, err := yahoo.Snapshot([]string{"AAPL"})
+	if err == nil || !strings.Contains(err.Error(), "symbols") {
+		t.Errorf("Snapshot error = %v, want Yahoo's description", err)
+	}
+}
@@ -12,10 +12,26 @@ type Source interface {
 	Expiration() time.Duration
 	// 获取公司每日报价
 	Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error)
-	// 最大并发数
-	ParallelMax() int
+	// 市场的最大并发数
+	ParallelMax(_market market.Market) int
 	// 失败重试次数
 	RetryCount() int
 	// 失败重试时间间隔
 	RetryInterval() time.Duration
 }
+
+// RunScoped 每次运行需要独立状态的数据源，如按次限制下载字节数
+type RunScoped interface {
+	// 返回本次运行使用的数据源
+	ForRun() Source
+}
+
+// ForRun 数据源支持时返回本次运行使用的数据源，否则原样返回
+func ForRun(source Source) Source {
+
+	if scoped, ok := source.(RunScoped); ok {
+		return scoped.ForRun()
+	}
+
+	return source
+}
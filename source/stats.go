@@ -0,0 +1,79 @@
+package source
+
+import (
+	"sync/atomic"
+)
+
+// DownloadStats 下载统计
+type DownloadStats struct {
+	Downloads uint64 // 下载次数
+	Requests  uint64 // 实际发出的请求数，包括重试
+	Coalesced uint64 // 与相同网址的下载合并而省去的下载次数
+	Failures  uint64 // 重试后仍然失败的下载次数
+	Bytes     int64  // 下载的字节数
+}
+
+// downloadCounters 下载计数，可以并发调用，为nil时不计数
+type downloadCounters struct {
+	downloads uint64
+	requests  uint64
+	coalesced uint64
+	failures  uint64
+	bytes     int64
+}
+
+// countDownload 下载次数加1
+func (c *downloadCounters) countDownload() {
+	if c != nil {
+		atomic.AddUint64(&c.downloads, 1)
+	}
+}
+
+// countRequest 请求数加1
+func (c *downloadCounters) countRequest() {
+	if c != nil {
+		atomic.AddUint64(&c.requests, 1)
+	}
+}
+
+// countCoalesced 合并次数加1
+func (c *downloadCounters) countCoalesced() {
+	if c != nil {
+		atomic.AddUint64(&c.coalesced, 1)
+	}
+}
+
+// countFailure 失败次数加1
+func (c *downloadCounters) countFailure() {
+	if c != nil {
+		atomic.AddUint64(&c.failures, 1)
+	}
+}
+
+// countBytes 累加下载的字节数
+func (c *downloadCounters) countBytes(size int) {
+	if c != nil {
+		atomic.AddInt64(&c.bytes, int64(size))
+	}
+}
+
+// snapshot 当前计数
+func (c *downloadCounters) snapshot() DownloadStats {
+
+	if c == nil {
+		return DownloadStats{}
+	}
+
+	return DownloadStats{
+		Downloads: atomic.LoadUint64(&c.downloads),
+		Requests:  atomic.LoadUint64(&c.requests),
+		Coalesced: atomic.LoadUint64(&c.coalesced),
+		Failures:  atomic.LoadUint64(&c.failures),
+		Bytes:     atomic.LoadInt64(&c.bytes),
+	}
+}
+
+// Stats 自创建以来的下载统计
+func (yahoo YahooFinance) Stats() DownloadStats {
+	return yahoo.counters.snapshot()
+}
@@ -0,0 +1,48 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 2, RetryInterval: time.Millisecond, DedupeTTL: time.Minute}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/throttled" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("chart"))
+	}))
+	defer server.Close()
+
+	if stats := yahoo.Stats(); stats != (DownloadStats{}) {
+		t.Errorf("initial stats = %+v, want zero", stats)
+	}
+
+	steps := []struct {
+		path     string
+		expected DownloadStats
+	}{
+		{"/AAPL", DownloadStats{Downloads: 1, Requests: 1, Bytes: 5}},
+		// 保留期内的相同网址不再请求
+		{"/AAPL", DownloadStats{Downloads: 2, Requests: 1, Coalesced: 1, Bytes: 5}},
+		{"/MSFT", DownloadStats{Downloads: 3, Requests: 2, Coalesced: 1, Bytes: 10}},
+		// 重试一次后仍然失败
+		{"/throttled", DownloadStats{Downloads: 4, Requests: 4, Coalesced: 1, Failures: 1, Bytes: 10}},
+	}
+
+	for _, step := range steps {
+		yahoo.downloadDay(context.Background(), server.URL+step.path)
+		if stats := yahoo.Stats(); stats != step.expected {
+			t.Errorf("after %s stats = %+v, want %+v", step.path, stats, step.expected)
+		}
+	}
+
+	// 未计数的数据源
+	if stats := (YahooFinance{}).Stats(); stats != (DownloadStats{}) {
+		t.Errorf("zero YahooFinance stats = %+v, want zero", stats)
+	}
+}
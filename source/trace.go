@@ -0,0 +1,21 @@
+package source
+
+import (
+	"context"
+)
+
+// Tracer 调用跟踪，可用于对接OpenTelemetry等分布式跟踪系统
+type Tracer interface {
+	// 开始一个跟踪区间，ctx中带有上级区间，返回带有新区间的ctx和结束该区间的函数
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, func(err error))
+}
+
+// startSpan 开始跟踪区间，未设置Tracer时原样返回ctx
+func startSpan(ctx context.Context, tracer Tracer, name string, attributes map[string]string) (context.Context, func(err error)) {
+
+	if tracer == nil {
+		return ctx, func(error) {}
+	}
+
+	return tracer.Start(ctx, name, attributes)
+}
@@ -0,0 +1,78 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// spanKey ctx中保存当前区间名称的键
+type spanKey struct{}
+
+// recordedSpan 记录的区间
+type recordedSpan struct {
+	name   string
+	parent string
+	ended  bool
+}
+
+// recordingTracer 记录区间及其上级区间
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+// Start 开始区间，上级区间取自ctx
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, func(error)) {
+
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent}
+
+	t.mutex.Lock()
+	t.spans = append(t.spans, span)
+	t.mutex.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, name), func(error) {
+		t.mutex.Lock()
+		span.ended = true
+		t.mutex.Unlock()
+	}
+}
+
+func TestCrawlSpans(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buffer)
+	}))
+	defer server.Close()
+
+	tracer := new(recordingTracer)
+	yahoo.SetTracer(tracer)
+
+	_, err := yahoo.Crawl(market.America{}, market.Company{Code: "AAPL", Name: "Apple"}, fixtureDate(t))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	// 下载和解析是Crawl的子区间，请求是下载的子区间
+	expected := []recordedSpan{
+		{name: "YahooFinance.Crawl", parent: "", ended: true},
+		{name: "YahooFinance.Download", parent: "YahooFinance.Crawl", ended: true},
+		{name: "YahooFinance.Request", parent: "YahooFinance.Download", ended: true},
+		{name: "YahooFinance.Parse", parent: "YahooFinance.Crawl", ended: true},
+	}
+
+	if len(tracer.spans) != len(expected) {
+		t.Fatalf("got %d spans, want %d", len(tracer.spans), len(expected))
+	}
+
+	for index, span := range tracer.spans {
+		if *span != expected[index] {
+			t.Errorf("span %d = %+v, want %+v", index, *span, expected[index])
+		}
+	}
+}
@@ -1,21 +1,163 @@
 package source
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/nzai/go-utility/net"
+	"github.com/nzai/go-utility/io"
 	"github.com/nzai/stockrecorder/market"
 )
 
+var (
+	// ErrNonTradingDay 休市日出现了报价
+	ErrNonTradingDay = errors.New("休市日出现了报价")
+
+	// ErrFutureDate 请求的日期还没有开始
+	ErrFutureDate = errors.New("请求的日期还没有开始")
+
+	// ErrByteBudgetExceeded 下载的字节数已超过限制
+	ErrByteBudgetExceeded = errors.New("下载的字节数已超过限制")
+)
+
+// SymbolResolver 将上市公司转换为雅虎查询代码
+type SymbolResolver func(_market market.Market, company market.Company) (string, error)
+
+const (
+	defaultYahooParallelMax   = 32
+	defaultYahooRetryCount    = 5
+	defaultYahooRetryInterval = time.Second * 10
+	defaultYahooIdleTimeout   = time.Second * 90
+	defaultYahooTimeout       = time.Second * 60
+)
+
+// YahooFinanceConfig 雅虎财经数据源配置，未设置的项使用默认值
+type YahooFinanceConfig struct {
+	ParallelMax         int               `yaml:"parallel"`            // 最大并发数
+	RetryCount          int               `yaml:"retry"`               // 失败重试次数
+	RetryInterval       time.Duration     `yaml:"retryinterval"`       // 失败重试时间间隔
+	MarketParallelMax   map[string]int    `yaml:"marketparallel"`      // 按市场名称设置的最大并发数，未设置的市场使用ParallelMax
+	VolumeMultipliers   map[string]uint32 `yaml:"volumemultipliers"`   // 按市场名称设置的成交量倍数，用于将手换算为股，默认为1
+	MaxIdleConnsPerHost int               `yaml:"maxidleconnsperhost"` // 每个主机保持的最大空闲连接数，默认与最大并发数相同
+	IdleConnTimeout     time.Duration     `yaml:"idleconntimeout"`     // 空闲连接超时
+	Timeout             time.Duration     `yaml:"timeout"`             // 每次请求的超时时间，超时后按失败重试
+	DedupeTTL           time.Duration     `yaml:"dedupettl"`           // 某天的分时数据下载成功后结果的保留时间，实时报价等不保留，为0时只合并同时进行的下载
+	TrimPartialBars     bool              `yaml:"trimpartialbars"`     // 删除各交易时段中被时段结束截断的最后一个报价
+	StrictDecoding      bool              `yaml:"strictdecoding"`      // 返回中出现未知字段时报错，用于及时发现接口变化，默认忽略未知字段
+	ByteBudget          int64             `yaml:"bytebudget"`          // 每次运行(ForRun)最多下载的字节数，超过后不再下载，为0时不限制
+}
+
 // YahooFinance 雅虎财经数据源
-type YahooFinance struct{}
+type YahooFinance struct {
+	config    YahooFinanceConfig
+	client    *http.Client      // 下载使用的客户端
+	resolver  SymbolResolver    // 查询代码转换
+	tracer    Tracer            // 调用跟踪
+	downloads *downloadGroup    // 合并相同网址的下载
+	counters  *downloadCounters // 下载统计
+	budget    *byteBudget       // 本次运行的下载字节数限制，不通过ForRun使用时由整个实例共用
+	now       func() time.Time  // 当前时间，用于判断请求的日期是否已经开盘
+}
 
 // NewYahooFinance 新建雅虎财经数据源
-func NewYahooFinance() YahooFinance {
-	return YahooFinance{}
+func NewYahooFinance(config YahooFinanceConfig) YahooFinance {
+
+	if config.ParallelMax <= 0 {
+		config.ParallelMax = defaultYahooParallelMax
+	}
+
+	if config.RetryCount <= 0 {
+		config.RetryCount = defaultYahooRetryCount
+	}
+
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultYahooRetryInterval
+	}
+
+	// 市场名称不区分大小写
+	parallels := make(map[string]int, len(config.MarketParallelMax))
+	for name, parallel := range config.MarketParallelMax {
+		parallels[strings.ToLower(name)] = parallel
+	}
+	config.MarketParallelMax = parallels
+
+	multipliers := make(map[string]uint32, len(config.VolumeMultipliers))
+	for name, multiplier := range config.VolumeMultipliers {
+		multipliers[strings.ToLower(name)] = multiplier
+	}
+	config.VolumeMultipliers = multipliers
+
+	if config.MaxIdleConnsPerHost <= 0 {
+		config.MaxIdleConnsPerHost = config.ParallelMax
+	}
+
+	if config.IdleConnTimeout <= 0 {
+		config.IdleConnTimeout = defaultYahooIdleTimeout
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultYahooTimeout
+	}
+
+	// 默认的Transport每个主机只保持2个空闲连接，高并发时会不断重建连接
+	// 其余设置(代理、拨号超时、TLS握手超时、HTTP/2等)沿用默认值
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.MaxIdleConnsPerHost * 2
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+
+	return YahooFinance{
+		config:    config,
+		client:    &http.Client{Transport: transport},
+		downloads: newDownloadGroup(config.DedupeTTL),
+		counters:  new(downloadCounters),
+		budget:    newByteBudget(config.ByteBudget),
+		now:       time.Now,
+	}
+}
+
+// volumeMultiplier 市场的成交量倍数
+func (yahoo YahooFinance) volumeMultiplier(_market market.Market) uint32 {
+
+	multiplier, found := yahoo.config.VolumeMultipliers[strings.ToLower(_market.Name())]
+	if !found || multiplier == 0 {
+		return 1
+	}
+
+	return multiplier
+}
+
+// SetSymbolResolver 设置查询代码转换，未设置时使用市场默认的雅虎查询代码
+func (yahoo *YahooFinance) SetSymbolResolver(resolver SymbolResolver) {
+	yahoo.resolver = resolver
+}
+
+// SetTracer 设置调用跟踪，Crawl的下载和解析区间是其子区间，每次请求又是下载区间的子区间
+func (yahoo *YahooFinance) SetTracer(tracer Tracer) {
+	yahoo.tracer = tracer
+}
+
+// queryCode 雅虎查询代码
+func (yahoo YahooFinance) queryCode(_market market.Market, company market.Company) (string, error) {
+
+	if yahoo.resolver == nil {
+		return market.CanonicalizeQueryCode(_market.YahooQueryCode(company))
+	}
+
+	code, err := yahoo.resolver(_market, company)
+	if err != nil {
+		return "", err
+	}
+
+	return market.CanonicalizeQueryCode(code)
 }
 
 // Expiration 最早能查到60天前的数据
@@ -24,36 +166,118 @@ func (yahoo YahooFinance) Expiration() time.Duration {
 }
 
 // Crawl 获取公司每天的报价
-func (yahoo YahooFinance) Crawl(_market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+func (yahoo YahooFinance) Crawl(_market market.Market, company market.Company, date time.Time) (cdq *market.CompanyDailyQuote, err error) {
 
 	// 起止时间
 	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 	end := start.AddDate(0, 0, 1)
 
+	// 日期按市场所在时区，当天开盘之后才可能有正常交易时段的报价，以免把未开盘的空结果当作休市保存
+	if !market.RegularOpen(_market, start).Before(yahoo.now()) {
+		return nil, ErrFutureDate
+	}
+
+	// 查询代码
+	queryCode, err := yahoo.queryCode(_market, company)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s的雅虎查询代码时发生错误: %v", company.Code, err)
+	}
+
 	pattern := "https://finance-yql.media.yahoo.com/v7/finance/chart/%s?period2=%d&period1=%d&interval=1m&indicators=quote&includeTimestamps=true&includePrePost=true&events=div%%7Csplit%%7Cearn&corsDomain=finance.yahoo.com"
-	url := fmt.Sprintf(pattern, _market.YahooQueryCode(company), end.Unix(), start.Unix())
+	url := fmt.Sprintf(pattern, queryCode, end.Unix(), start.Unix())
+
+	ctx, endSpan := startSpan(context.Background(), yahoo.tracer, "YahooFinance.Crawl", map[string]string{
+		"market": _market.Name(),
+		"code":   company.Code,
+		"day":    date.Format("20060102"),
+		"url":    url,
+	})
+	defer func() { endSpan(err) }()
 
 	// 查询Yahoo财经接口,返回股票分时数据
-	str, err := net.DownloadStringRetry(url, yahoo.RetryCount(), yahoo.RetryInterval())
+	buffer, err := yahoo.downloadDay(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
+	_, endParseSpan := startSpan(ctx, yahoo.tracer, "YahooFinance.Parse", map[string]string{"code": company.Code})
+	cdq, err = yahoo.Parse(_market, company, date, buffer)
+	endParseSpan(err)
+
+	return cdq, err
+}
+
+// Parse 解析雅虎财经返回的json，只做解析不访问网络和存储
+func (yahoo YahooFinance) Parse(_market market.Market, company market.Company, date time.Time, buffer []byte) (*market.CompanyDailyQuote, error) {
+
 	// 解析Json
 	quote := &YahooQuote{}
-	err = json.Unmarshal([]byte(str), &quote)
+	err := yahoo.decode(buffer, quote)
 	if err != nil {
 		return nil, err
 	}
 
+	// 部分接口返回毫秒时间戳
+	quote.NormalizeTimestamps()
+
+	// 成交清淡的证券偶尔整个成交量数组为null，价格正常时成交量按0处理
+	if quote.FillMissingVolume() {
+		log.Printf("[%s] %s的成交量数组缺失，成交量按0处理", _market.Name(), company.Code)
+	}
+
 	// 校验
 	err = yahoo.valid(quote)
 	if err != nil {
 		return nil, err
 	}
 
+	// 时区与偏移不一致时仅提示，报价时间为UTC时间戳，不受影响
+	if delta, mismatch := quote.TimezoneMismatch(); mismatch {
+		log.Printf("[%s] %s的gmtoffset与时区%s不一致，相差%s", _market.Name(), company.Code, quote.Chart.Result[0].Meta.ExchangeTimezoneName, delta.String())
+	}
+
+	// 报价时间不在分钟整点上通常是数据损坏，仅提示
+	if count := quote.MisalignedTimestamps(); count > 0 {
+		log.Printf("[%s] %s有%d个报价时间与交易时段起点不是整数个间隔", _market.Name(), company.Code, count)
+	}
+
 	// 解析
-	return yahoo.parse(_market, company, date, quote)
+	cdq, err := yahoo.parse(_market, company, date, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	// 周末出现的报价为错误数据
+	weekday := date.Weekday()
+	if (weekday == time.Saturday || weekday == time.Sunday) &&
+		!tradesAroundTheClock(quote.Chart.Result[0].Meta.InstrumentType) &&
+		cdq.Pre.Count+cdq.Regular.Count+cdq.Post.Count > 0 {
+		return nil, ErrNonTradingDay
+	}
+
+	return cdq, nil
+}
+
+// decode 解析Json，严格模式下不允许未知字段
+func (yahoo YahooFinance) decode(buffer []byte, value interface{}) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(buffer))
+	if yahoo.config.StrictDecoding {
+		decoder.DisallowUnknownFields()
+	}
+
+	return decoder.Decode(value)
+}
+
+// ParseFile 解析保存在文件中的雅虎财经原始返回，用于离线重现解析问题
+func (yahoo YahooFinance) ParseFile(filePath string, _market market.Market, company market.Company, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	buffer, err := io.ReadAllBytes(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return yahoo.Parse(_market, company, date, buffer)
 }
 
 // valid 校验
@@ -76,6 +300,22 @@ func (yahoo YahooFinance) valid(quote *YahooQuote) error {
 
 	result, _quote := quote.Chart.Result[0], quote.Chart.Result[0].Indicators.Quotes[0]
 
+	// 有时间但整个报价数组为null
+	if len(result.Timestamp) > 0 {
+		switch {
+		case _quote.Open == nil:
+			return errors.New("报价数组open缺失")
+		case _quote.Close == nil:
+			return errors.New("报价数组close缺失")
+		case _quote.High == nil:
+			return errors.New("报价数组high缺失")
+		case _quote.Low == nil:
+			return errors.New("报价数组low缺失")
+		case _quote.Volume == nil:
+			return errors.New("报价数组volume缺失")
+		}
+	}
+
 	// Quotes数量不正确
 	if len(result.Timestamp) != len(_quote.Open) ||
 		len(result.Timestamp) != len(_quote.Close) ||
@@ -95,60 +335,162 @@ func (yahoo YahooFinance) valid(quote *YahooQuote) error {
 		return errors.New("TradingPeriods数量不正确")
 	}
 
+	// 收盘价数组错位
+	if yahoo.misaligned(_quote.Close, _quote.High, _quote.Low) {
+		return errors.New("收盘价数组疑似与最高最低价错位")
+	}
+
+	// 交易时段长度不合理
+	err := quote.ValidateSession()
+	if err != nil {
+		return err
+	}
+
+	// 存储格式按分钟记录，雅虎对较早日期可能降级为更粗的粒度
+	if result.Meta.DataGranularity != "" && result.Meta.DataGranularity != "1m" {
+		return fmt.Errorf("数据粒度不正确: %s", result.Meta.DataGranularity)
+	}
+
 	return nil
 }
 
+// misaligned 收盘价不在最高最低价之间的报价超过一半时，认为数组错位
+func (yahoo YahooFinance) misaligned(closes, highs, lows []float32) bool {
+
+	var checked, outside int
+	for index := range closes {
+
+		// 忽略空报价
+		if closes[index] == 0 || highs[index] == 0 || lows[index] == 0 {
+			continue
+		}
+		checked++
+
+		if closes[index] > highs[index] || closes[index] < lows[index] {
+			outside++
+		}
+	}
+
+	// 报价太少时不判断
+	return checked >= 10 && outside*2 > checked
+}
+
 // parse 解析结果
 func (yahoo YahooFinance) parse(_market market.Market, company market.Company, date time.Time, quote *YahooQuote) (*market.CompanyDailyQuote, error) {
 
+	result := &quote.Chart.Result[0]
+	meta := &result.Meta
+
+	// 上市公司列表中没有名称时使用雅虎返回的名称
+	if company.Name == "" {
+		company.Name = meta.LongName
+		if company.Name == "" {
+			company.Name = meta.ShortName
+		}
+	}
+
 	companyDailyQuote := market.CompanyDailyQuote{Company: company}
 
-	periods, _quote := quote.Chart.Result[0].Meta.TradingPeriods, quote.Chart.Result[0].Indicators.Quotes[0]
-	for index, ts := range quote.Chart.Result[0].Timestamp {
+	timestamps, _quote := result.Timestamp, &result.Indicators.Quotes[0]
+	pre, regular, post := meta.TradingPeriods.Pres[0][0], meta.TradingPeriods.Regulars[0][0], meta.TradingPeriods.Posts[0][0]
+	multiplier := yahoo.volumeMultiplier(_market)
+
+	//	Pre, Regular, Post
+	sessions := make([]*market.QuoteSeries, len(timestamps))
+	var preCount, regularCount, postCount int
+	for index, ts := range timestamps {
 
 		//	如果全为0就忽略
 		if _quote.Open[index] == 0 && _quote.Close[index] == 0 && _quote.High[index] == 0 && _quote.Low[index] == 0 && _quote.Volume[index] == 0 {
 			continue
 		}
 
-		var series *market.QuoteSeries
+		if ts >= pre.Start && ts < pre.End {
+			sessions[index] = &companyDailyQuote.Pre
+			preCount++
+		} else if ts >= regular.Start && ts < regular.End {
+			sessions[index] = &companyDailyQuote.Regular
+			regularCount++
+		} else if ts >= post.Start && ts < post.End {
+			sessions[index] = &companyDailyQuote.Post
+			postCount++
+		}
+	}
+
+	// 按数量一次性分配
+	allocSeries(&companyDailyQuote.Pre, preCount)
+	allocSeries(&companyDailyQuote.Regular, regularCount)
+	allocSeries(&companyDailyQuote.Post, postCount)
+
+	for index, series := range sessions {
 
-		//	Pre, Regular, Post
-		if ts >= periods.Pres[0][0].Start && ts < periods.Pres[0][0].End {
-			series = &companyDailyQuote.Pre
-		} else if ts >= periods.Regulars[0][0].Start && ts < periods.Regulars[0][0].End {
-			series = &companyDailyQuote.Regular
-		} else if ts >= periods.Posts[0][0].Start && ts < periods.Posts[0][0].End {
-			series = &companyDailyQuote.Post
-		} else {
+		if series == nil {
 			continue
 		}
 
 		series.Count++
-		series.Timestamp = append(series.Timestamp, uint32(ts))
+		series.Timestamp = append(series.Timestamp, uint32(timestamps[index]))
 		series.Open = append(series.Open, uint32(_quote.Open[index]*100))
 		series.Close = append(series.Close, uint32(_quote.Close[index]*100))
 		series.Max = append(series.Max, uint32(_quote.High[index]*100))
 		series.Min = append(series.Min, uint32(_quote.Low[index]*100))
-		series.Volume = append(series.Volume, uint32(_quote.Volume[index]))
+		series.Volume = append(series.Volume, uint32(_quote.Volume[index])*multiplier)
+	}
+
+	// 雅虎偶尔会重复返回同一分钟的报价
+	companyDailyQuote.Pre.Deduplicate()
+	companyDailyQuote.Regular.Deduplicate()
+	companyDailyQuote.Post.Deduplicate()
+
+	// 时段结束不在整分钟时，最后一分钟的成交量很小，会影响收盘价的计算
+	if yahoo.config.TrimPartialBars {
+		session := func(start, end int64) market.Session {
+			return market.Session{Start: time.Unix(start, 0), End: time.Unix(end, 0)}
+		}
+
+		companyDailyQuote.Pre.TrimPartialLast(session(pre.Start, pre.End), time.Minute)
+		companyDailyQuote.Regular.TrimPartialLast(session(regular.Start, regular.End), time.Minute)
+		companyDailyQuote.Post.TrimPartialLast(session(post.Start, post.End), time.Minute)
 	}
 
 	return &companyDailyQuote, nil
 }
 
-// ParallelMax 最大并发数
-func (yahoo YahooFinance) ParallelMax() int {
-	return 32
+// allocSeries 为报价序列分配容量，所有数组共用一块内存
+func allocSeries(series *market.QuoteSeries, capacity int) {
+
+	if capacity == 0 {
+		return
+	}
+
+	values := make([]uint32, capacity*6)
+	series.Timestamp = values[0:0:capacity]
+	series.Open = values[capacity : capacity : capacity*2]
+	series.Close = values[capacity*2 : capacity*2 : capacity*3]
+	series.Max = values[capacity*3 : capacity*3 : capacity*4]
+	series.Min = values[capacity*4 : capacity*4 : capacity*5]
+	series.Volume = values[capacity*5 : capacity*5 : capacity*6]
+}
+
+// ParallelMax 市场的最大并发数
+func (yahoo YahooFinance) ParallelMax(_market market.Market) int {
+
+	parallel, found := yahoo.config.MarketParallelMax[strings.ToLower(_market.Name())]
+	if !found || parallel <= 0 {
+		return yahoo.config.ParallelMax
+	}
+
+	return parallel
 }
 
 // RetryCount 失败重试次数
 func (yahoo YahooFinance) RetryCount() int {
-	return 5
+	return yahoo.config.RetryCount
 }
 
 // RetryInterval 失败重试时间间隔
 func (yahoo YahooFinance) RetryInterval() time.Duration {
-	return time.Second * 10
+	return yahoo.config.RetryInterval
 }
 
 // YahooQuote 雅虎财经返回的json
@@ -156,15 +498,29 @@ type YahooQuote struct {
 	Chart struct {
 		Result []struct {
 			Meta struct {
-				Currency             string  `json:"currency"`
-				Symbol               string  `json:"symbol"`
-				ExchangeName         string  `json:"exchangeName"`
-				InstrumentType       string  `json:"instrumentType"`
-				FirstTradeDate       int64   `json:"firstTradeDate"`
-				GMTOffset            int64   `json:"gmtoffset"`
-				Timezone             string  `json:"timezone"`
-				PreviousClose        float32 `json:"previousClose"`
-				Scale                int     `json:"scale"`
+				Currency             string     `json:"currency"`
+				Symbol               string     `json:"symbol"`
+				ShortName            string     `json:"shortName"`
+				LongName             string     `json:"longName"`
+				ExchangeName         string     `json:"exchangeName"`
+				FullExchangeName     string     `json:"fullExchangeName"`
+				InstrumentType       string     `json:"instrumentType"`
+				FirstTradeDate       int64      `json:"firstTradeDate"`
+				RegularMarketTime    int64      `json:"regularMarketTime"`
+				HasPrePostMarketData bool       `json:"hasPrePostMarketData"`
+				GMTOffset            int64      `json:"gmtoffset"`
+				Timezone             string     `json:"timezone"`
+				ExchangeTimezoneName string     `json:"exchangeTimezoneName"`
+				RegularMarketPrice   YahooFloat `json:"regularMarketPrice"`
+				FiftyTwoWeekHigh     YahooFloat `json:"fiftyTwoWeekHigh"`
+				FiftyTwoWeekLow      YahooFloat `json:"fiftyTwoWeekLow"`
+				RegularMarketDayHigh YahooFloat `json:"regularMarketDayHigh"`
+				RegularMarketDayLow  YahooFloat `json:"regularMarketDayLow"`
+				RegularMarketVolume  int64      `json:"regularMarketVolume"`
+				ChartPreviousClose   YahooFloat `json:"chartPreviousClose"`
+				PreviousClose        YahooFloat `json:"previousClose"`
+				Scale                int        `json:"scale"`
+				PriceHint            int        `json:"priceHint"`
 				CurrentTradingPeriod struct {
 					Pre struct {
 						Timezone  string `json:"timezone"`
@@ -206,6 +562,7 @@ type YahooQuote struct {
 					} `json:"post"`
 				} `json:"tradingPeriods"`
 				DataGranularity string   `json:"dataGranularity"`
+				Range           string   `json:"range"`
 				ValidRanges     []string `json:"validRanges"`
 			} `json:"meta"`
 			Timestamp  []int64 `json:"timestamp"`
@@ -217,7 +574,25 @@ type YahooQuote struct {
 					Low    []float32 `json:"low"`
 					Volume []int64   `json:"volume"`
 				} `json:"quote"`
+				AdjustedCloses []struct {
+					AdjustedClose []float32 `json:"adjclose"`
+				} `json:"adjclose"`
 			} `json:"indicators"`
+			Events struct {
+				Dividends map[string]struct {
+					Amount YahooFloat `json:"amount"`
+					Date   int64      `json:"date"`
+				} `json:"dividends"`
+				Splits map[string]struct {
+					Date        int64  `json:"date"`
+					Numerator   int    `json:"numerator"`
+					Denominator int    `json:"denominator"`
+					SplitRatio  string `json:"splitRatio"`
+				} `json:"splits"`
+				Earnings map[string]struct {
+					Date int64 `json:"date"`
+				} `json:"earnings"`
+			} `json:"events"`
 		} `json:"result"`
 		Err *struct {
 			Code        string `json:"code"`
@@ -225,3 +600,290 @@ type YahooQuote struct {
 		} `json:"error"`
 	} `json:"chart"`
 }
+
+// TradingSessions 当天的交易时段，时间为交易所所在时区
+func (quote YahooQuote) TradingSessions() (*market.TradingSessions, error) {
+
+	if len(quote.Chart.Result) == 0 {
+		return nil, errors.New("quote.Chart.Result is null")
+	}
+
+	meta := quote.Chart.Result[0].Meta
+	periods := meta.TradingPeriods
+	if len(periods.Pres) == 0 || len(periods.Pres[0]) == 0 ||
+		len(periods.Regulars) == 0 || len(periods.Regulars[0]) == 0 ||
+		len(periods.Posts) == 0 || len(periods.Posts[0]) == 0 {
+		return nil, errors.New("TradingPeriods数量不正确")
+	}
+
+	// 优先使用IANA时区名称，其次根据查询代码后缀判断，最后使用时区缩写和偏移
+	timezone := meta.ExchangeTimezoneName
+	if timezone == "" {
+		_, timezone, _ = market.ExchangeFromQueryCode(meta.Symbol)
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if timezone == "" || err != nil {
+		location = time.FixedZone(meta.Timezone, int(meta.GMTOffset))
+	}
+
+	session := func(start, end int64) market.Session {
+		return market.Session{
+			Start: time.Unix(start, 0).In(location),
+			End:   time.Unix(end, 0).In(location),
+		}
+	}
+
+	return &market.TradingSessions{
+		Pre:     session(periods.Pres[0][0].Start, periods.Pres[0][0].End),
+		Regular: session(periods.Regulars[0][0].Start, periods.Regulars[0][0].End),
+		Post:    session(periods.Posts[0][0].Start, periods.Posts[0][0].End),
+	}, nil
+}
+
+// Earnings 返回中的财报发布时间，按时间排列
+func (quote YahooQuote) Earnings() []time.Time {
+
+	if len(quote.Chart.Result) == 0 {
+		return nil
+	}
+
+	var dates []time.Time
+	for key, earning := range quote.Chart.Result[0].Events.Earnings {
+
+		// 没有date时使用键中的时间戳
+		timestamp := earning.Date
+		if timestamp == 0 {
+			timestamp, _ = strconv.ParseInt(key, 10, 64)
+			timestamp = secondsTimestamp(timestamp)
+		}
+
+		if timestamp > 0 {
+			dates = append(dates, time.Unix(timestamp, 0))
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	return dates
+}
+
+// ParseEarnings 解析雅虎财经返回的json中的财报发布时间
+func (yahoo YahooFinance) ParseEarnings(buffer []byte) ([]time.Time, error) {
+
+	quote := &YahooQuote{}
+	err := yahoo.decode(buffer, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	if quote.Chart.Err != nil {
+		return nil, errors.New(quote.Chart.Err.Description)
+	}
+
+	quote.NormalizeTimestamps()
+
+	return quote.Earnings(), nil
+}
+
+// FillMissingVolume 有时间和价格但成交量数组为null时，将成交量全部设为0，返回是否填充
+func (quote *YahooQuote) FillMissingVolume() bool {
+
+	if len(quote.Chart.Result) == 0 || len(quote.Chart.Result[0].Indicators.Quotes) == 0 {
+		return false
+	}
+
+	result := &quote.Chart.Result[0]
+	_quote := &result.Indicators.Quotes[0]
+	if len(result.Timestamp) == 0 || _quote.Volume != nil ||
+		_quote.Open == nil || _quote.Close == nil || _quote.High == nil || _quote.Low == nil {
+		return false
+	}
+
+	_quote.Volume = make([]int64, len(result.Timestamp))
+
+	return true
+}
+
+const (
+	// maxSecondsTimestamp 超过此值的时间戳按毫秒处理，以秒计为5138年
+	maxSecondsTimestamp = 100000000000
+)
+
+// secondsTimestamp 将毫秒时间戳转换为秒
+func secondsTimestamp(ts int64) int64 {
+	if ts > maxSecondsTimestamp || ts < -maxSecondsTimestamp {
+		return ts / 1000
+	}
+
+	return ts
+}
+
+// NormalizeTimestamps 将以毫秒表示的报价时间和交易时段统一转换为秒
+func (quote *YahooQuote) NormalizeTimestamps() {
+
+	for index := range quote.Chart.Result {
+
+		result := &quote.Chart.Result[index]
+		for i, ts := range result.Timestamp {
+			result.Timestamp[i] = secondsTimestamp(ts)
+		}
+
+		for key, dividend := range result.Events.Dividends {
+			dividend.Date = secondsTimestamp(dividend.Date)
+			result.Events.Dividends[key] = dividend
+		}
+		for key, split := range result.Events.Splits {
+			split.Date = secondsTimestamp(split.Date)
+			result.Events.Splits[key] = split
+		}
+		for key, earning := range result.Events.Earnings {
+			earning.Date = secondsTimestamp(earning.Date)
+			result.Events.Earnings[key] = earning
+		}
+
+		meta := &result.Meta
+		meta.FirstTradeDate = secondsTimestamp(meta.FirstTradeDate)
+		meta.RegularMarketTime = secondsTimestamp(meta.RegularMarketTime)
+
+		current := &meta.CurrentTradingPeriod
+		current.Pre.Start, current.Pre.End = secondsTimestamp(current.Pre.Start), secondsTimestamp(current.Pre.End)
+		current.Regular.Start, current.Regular.End = secondsTimestamp(current.Regular.Start), secondsTimestamp(current.Regular.End)
+		current.Post.Start, current.Post.End = secondsTimestamp(current.Post.Start), secondsTimestamp(current.Post.End)
+
+		periods := &meta.TradingPeriods
+		for _, days := range periods.Pres {
+			for i := range days {
+				days[i].Start, days[i].End = secondsTimestamp(days[i].Start), secondsTimestamp(days[i].End)
+			}
+		}
+		for _, days := range periods.Regulars {
+			for i := range days {
+				days[i].Start, days[i].End = secondsTimestamp(days[i].Start), secondsTimestamp(days[i].End)
+			}
+		}
+		for _, days := range periods.Posts {
+			for i := range days {
+				days[i].Start, days[i].End = secondsTimestamp(days[i].Start), secondsTimestamp(days[i].End)
+			}
+		}
+	}
+}
+
+// tradesAroundTheClock 加密货币、外汇和期货可以全天及周末交易
+func tradesAroundTheClock(instrumentType string) bool {
+	switch instrumentType {
+	case "CRYPTOCURRENCY", "CURRENCY", "FUTURE":
+		return true
+	}
+
+	return false
+}
+
+// ValidateSession 校验正常交易时段的长度是否合理，避免错误的交易时段元数据污染数据
+func (quote YahooQuote) ValidateSession() error {
+
+	sessions, err := quote.TradingSessions()
+	if err != nil {
+		return err
+	}
+
+	// 其他证券正常交易时段不超过12小时
+	min, max := time.Minute*30, time.Hour*12
+	if tradesAroundTheClock(quote.Chart.Result[0].Meta.InstrumentType) {
+		max = time.Hour * 24
+	}
+
+	duration := sessions.Regular.Duration()
+	if duration < min || duration > max {
+		return fmt.Errorf("正常交易时段长度不合理: %s", duration.String())
+	}
+
+	return nil
+}
+
+// MisalignedTimestamps 各交易时段内与时段起点相差不是整数分钟的报价数量，valid只接受1分钟的数据粒度
+func (quote YahooQuote) MisalignedTimestamps() int {
+
+	if len(quote.Chart.Result) == 0 {
+		return 0
+	}
+
+	result := quote.Chart.Result[0]
+	periods := result.Meta.TradingPeriods
+	if len(periods.Pres) == 0 || len(periods.Pres[0]) == 0 ||
+		len(periods.Regulars) == 0 || len(periods.Regulars[0]) == 0 ||
+		len(periods.Posts) == 0 || len(periods.Posts[0]) == 0 {
+		return 0
+	}
+
+	step := int64(time.Minute / time.Second)
+
+	sessions := [][2]int64{
+		{periods.Pres[0][0].Start, periods.Pres[0][0].End},
+		{periods.Regulars[0][0].Start, periods.Regulars[0][0].End},
+		{periods.Posts[0][0].Start, periods.Posts[0][0].End},
+	}
+
+	var count int
+	for _, ts := range result.Timestamp {
+		for _, period := range sessions {
+			if ts >= period[0] && ts < period[1] {
+				if (ts-period[0])%step != 0 {
+					count++
+				}
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+// TimezoneMismatch 按交易所时区计算当天应有的偏移，与gmtoffset相差超过1小时时返回差值
+func (quote YahooQuote) TimezoneMismatch() (time.Duration, bool) {
+
+	if len(quote.Chart.Result) == 0 {
+		return 0, false
+	}
+
+	meta := quote.Chart.Result[0].Meta
+	if meta.ExchangeTimezoneName == "" || len(meta.TradingPeriods.Regulars) == 0 || len(meta.TradingPeriods.Regulars[0]) == 0 {
+		return 0, false
+	}
+
+	location, err := time.LoadLocation(meta.ExchangeTimezoneName)
+	if err != nil {
+		return 0, false
+	}
+
+	_, offset := time.Unix(meta.TradingPeriods.Regulars[0][0].Start, 0).In(location).Zone()
+	delta := time.Duration(int64(offset)-meta.GMTOffset) * time.Second
+	if delta > time.Hour || delta < -time.Hour {
+		return delta, true
+	}
+
+	return 0, false
+}
+
+// YahooFloat 兼容数字和字符串两种json表示的浮点数
+type YahooFloat float32
+
+// UnmarshalJSON 反序列化
+func (f *YahooFloat) UnmarshalJSON(data []byte) error {
+
+	// 部分地区的镜像会把数字以字符串返回
+	text := strings.TrimSpace(strings.Trim(strings.TrimSpace(string(data)), `"`))
+	if text == "" || text == "null" {
+		*f = 0
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(text, 32)
+	if err != nil {
+		return fmt.Errorf("错误的浮点数格式: %s", string(data))
+	}
+
+	*f = YahooFloat(value)
+	return nil
+}
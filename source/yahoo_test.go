@@ -0,0 +1,955 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// readFixture 读取testdata中的雅虎返回
+func readFixture(t testing.TB, name string) []byte {
+	t.Helper()
+
+	buffer, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+
+	return buffer
+}
+
+// patchFixture 替换雅虎返回中的一段内容，用于构造异常返回
+func patchFixture(t *testing.T, buffer []byte, old, new string) []byte {
+	t.Helper()
+
+	if !bytes.Contains(buffer, []byte(old)) {
+		t.Fatalf("fixture does not contain %q", old)
+	}
+
+	return bytes.Replace(buffer, []byte(old), []byte(new), 1)
+}
+
+// redirectTransport 把所有请求转发到测试服务器，保留路径和参数
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+// RoundTrip 转发请求
+func (t redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	request = request.Clone(request.Context())
+	request.URL.Scheme = target.Scheme
+	request.URL.Host = target.Host
+
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+// newTestYahooFinance 新建访问测试服务器的数据源
+func newTestYahooFinance(config YahooFinanceConfig, handler http.Handler) (YahooFinance, *httptest.Server) {
+
+	server := httptest.NewServer(handler)
+	yahoo := NewYahooFinance(config)
+	yahoo.client = &http.Client{Transport: redirectTransport{server: server}}
+
+	return yahoo, server
+}
+
+// fixtureDate chart_1m.json对应的交易日
+func fixtureDate(t testing.TB) time.Time {
+	t.Helper()
+
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	return time.Date(2017, 6, 1, 0, 0, 0, 0, location)
+}
+
+func TestStrictDecoding(t *testing.T) {
+
+	minute := readFixture(t, "chart_1m.json")
+	daily := readFixture(t, "chart_1d.json")
+	drifted := bytes.Replace(minute, []byte(`"priceHint": 2,`), []byte(`"priceHint": 2, "newThing": true,`), 1)
+
+	lenient := NewYahooFinance(YahooFinanceConfig{})
+	strict := NewYahooFinance(YahooFinanceConfig{StrictDecoding: true})
+	company := market.Company{Code: "AAPL", Name: "Apple"}
+	date := fixtureDate(t)
+
+	// 雅虎正常返回的字段在严格模式下也能解析
+	if _, err := strict.Parse(market.America{}, company, date, minute); err != nil {
+		t.Errorf("strict Parse of a standard response: %v", err)
+	}
+
+	if _, err := strict.ParseDailyBars(market.America{}, daily); err != nil {
+		t.Errorf("strict ParseDailyBars of a standard response: %v", err)
+	}
+
+	// 出现未知字段时只有严格模式报错
+	if _, err := lenient.Parse(market.America{}, company, date, drifted); err != nil {
+		t.Errorf("lenient Parse with an unknown field: %v", err)
+	}
+
+	_, err := strict.Parse(market.America{}, company, date, drifted)
+	if err == nil || !strings.Contains(err.Error(), "newThing") {
+		t.Errorf("strict Parse with an unknown field: err = %v, want unknown field newThing", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m_events.json")
+
+	// 分红、拆股和财报都是雅虎正常返回的事件
+	if drift := DetectSchema(buffer); len(drift) != 0 {
+		t.Errorf("DetectSchema reported drift on dividends, splits and earnings: %v", drift)
+	}
+
+	strict := NewYahooFinance(YahooFinanceConfig{StrictDecoding: true})
+	if _, err := strict.Parse(market.America{}, market.Company{Code: "AAPL"}, fixtureDate(t), buffer); err != nil {
+		t.Errorf("strict Parse of a response with events: %v", err)
+	}
+
+	// 财报时间为毫秒
+	earnings, err := strict.ParseEarnings(buffer)
+	if err != nil {
+		t.Fatalf("ParseEarnings: %v", err)
+	}
+
+	want := time.Date(2017, 6, 1, 13, 30, 0, 0, time.UTC)
+	if len(earnings) != 1 || !earnings[0].Equal(want) {
+		t.Errorf("earnings = %v, want [%s]", earnings, want)
+	}
+}
+
+func TestTransport(t *testing.T) {
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{ParallelMax: 8, IdleConnTimeout: 30 * time.Second})
+	transport, ok := yahoo.client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport is %T, want *http.Transport", yahoo.client.Transport)
+	}
+
+	// 只修改连接池设置
+	if transport.MaxIdleConns != 16 || transport.MaxIdleConnsPerHost != 8 || transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("pool = %d/%d/%s, want 16/8/30s", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+
+	// 其余沿用默认的Transport
+	defaults := http.DefaultTransport.(*http.Transport)
+	if transport.Proxy == nil || transport.DialContext == nil {
+		t.Error("proxy and dialer should come from the default transport")
+	}
+
+	if transport.TLSHandshakeTimeout != defaults.TLSHandshakeTimeout || transport.ExpectContinueTimeout != defaults.ExpectContinueTimeout {
+		t.Errorf("timeouts = %s/%s, want %s/%s", transport.TLSHandshakeTimeout, transport.ExpectContinueTimeout, defaults.TLSHandshakeTimeout, defaults.ExpectContinueTimeout)
+	}
+
+	if transport.ForceAttemptHTTP2 != defaults.ForceAttemptHTTP2 {
+		t.Errorf("ForceAttemptHTTP2 = %v, want %v", transport.ForceAttemptHTTP2, defaults.ForceAttemptHTTP2)
+	}
+
+	if transport == defaults {
+		t.Error("the default transport must not be modified in place")
+	}
+}
+
+func TestDataGranularity(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	company := market.Company{Code: "AAPL"}
+	date := fixtureDate(t)
+
+	tests := []struct {
+		granularity string
+		valid       bool
+	}{
+		{`"1m"`, true},
+		// 旧版接口不返回粒度
+		{`""`, true},
+		{`"2m"`, false},
+		{`"1d"`, false},
+	}
+
+	for _, test := range tests {
+
+		patched := patchFixture(t, buffer, `"dataGranularity": "1m"`, `"dataGranularity": `+test.granularity)
+		_, err := yahoo.Parse(market.America{}, company, date, patched)
+		if (err == nil) != test.valid {
+			t.Errorf("dataGranularity %s: err = %v, want valid = %v", test.granularity, err, test.valid)
+		}
+	}
+}
+
+func TestYahooFloat(t *testing.T) {
+
+	tests := []struct {
+		json     string
+		expected YahooFloat
+		valid    bool
+	}{
+		{`152.76`, 152.76, true},
+		{`"152.76"`, 152.76, true},
+		{`" 152.76 "`, 152.76, true},
+		{`""`, 0, true},
+		{`null`, 0, true},
+		{`"1,234.5"`, 0, false},
+		{`"abc"`, 0, false},
+	}
+
+	for _, test := range tests {
+
+		var value YahooFloat
+		err := value.UnmarshalJSON([]byte(test.json))
+		if (err == nil) != test.valid {
+			t.Errorf("UnmarshalJSON(%s): err = %v, want valid = %v", test.json, err, test.valid)
+			continue
+		}
+
+		if value != test.expected {
+			t.Errorf("UnmarshalJSON(%s) = %v, want %v", test.json, value, test.expected)
+		}
+	}
+}
+
+func TestStringPreviousClose(t *testing.T) {
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{StrictDecoding: true})
+
+	// 部分镜像把previousClose以字符串返回
+	for _, name := range []string{"chart_1m.json", "chart_1m_string_close.json"} {
+
+		quote := &YahooQuote{}
+		if err := yahoo.decode(readFixture(t, name), quote); err != nil {
+			t.Fatalf("decode %s: %v", name, err)
+		}
+
+		meta := quote.Chart.Result[0].Meta
+		if meta.PreviousClose != 152.76 || meta.ChartPreviousClose != 152.76 {
+			t.Errorf("%s: previousClose = %v/%v, want 152.76", name, meta.PreviousClose, meta.ChartPreviousClose)
+		}
+
+		if _, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL"}, fixtureDate(t), readFixture(t, name)); err != nil {
+			t.Errorf("Parse %s: %v", name, err)
+		}
+	}
+}
+
+func TestSymbolResolver(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+
+	var paths []string
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write(buffer)
+	}))
+	defer server.Close()
+
+	company := market.Company{Code: "BRK.B"}
+
+	// 未设置时使用市场默认的查询代码
+	code, err := yahoo.queryCode(market.America{}, company)
+	if err != nil || code != "BRK.B" {
+		t.Errorf("default queryCode = %q, %v, want BRK.B", code, err)
+	}
+
+	// 转换结果同样会被规范化
+	yahoo.SetSymbolResolver(func(_market market.Market, company market.Company) (string, error) {
+		return " " + strings.Replace(company.Code, ".", "-", -1) + " ", nil
+	})
+
+	if _, err = yahoo.Crawl(market.America{}, company, fixtureDate(t)); err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	if len(paths) != 1 || paths[0] != "/v7/finance/chart/BRK-B" {
+		t.Errorf("requested %v, want [/v7/finance/chart/BRK-B]", paths)
+	}
+
+	// 转换出错时不发出请求
+	yahoo.SetSymbolResolver(func(_market market.Market, company market.Company) (string, error) {
+		return "", errors.New("no mapping")
+	})
+
+	if _, err = yahoo.Crawl(market.America{}, company, fixtureDate(t)); err == nil || !strings.Contains(err.Error(), "no mapping") {
+		t.Errorf("Crawl with a failing resolver: err = %v, want the resolver error", err)
+	}
+
+	if len(paths) != 1 {
+		t.Errorf("a failing resolver should not send requests, got %v", paths)
+	}
+}
+
+func TestCrawlRangeAcrossDST(t *testing.T) {
+
+	var query url.Values
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"chart": {"result": [], "error": null}}`))
+	}))
+	defer server.Close()
+
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2017-03-12 美国开始夏令时，当天从EST 0点到EDT 0点只有23小时
+	yahoo.Crawl(market.America{}, market.Company{Code: "AAPL"}, time.Date(2017, 3, 12, 0, 0, 0, 0, location))
+
+	if query.Get("period1") != "1489294800" || query.Get("period2") != "1489377600" {
+		t.Errorf("period = [%s, %s), want [1489294800, 1489377600)", query.Get("period1"), query.Get("period2"))
+	}
+}
+
+func TestCompanyName(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	shortOnly := patchFixture(t, buffer, `"longName": "Apple Inc.",`, ``)
+	shortOnly = patchFixture(t, shortOnly, `"shortName": "Apple Inc."`, `"shortName": "Apple"`)
+	nameless := patchFixture(t, shortOnly, `"shortName": "Apple",`, ``)
+
+	tests := []struct {
+		name     string
+		buffer   []byte
+		company  string
+		expected string
+	}{
+		{"long name", buffer, "", "Apple Inc."},
+		{"short name only", shortOnly, "", "Apple"},
+		{"no name", nameless, "", ""},
+		// 上市公司列表中的名称优先
+		{"listed name", buffer, "苹果", "苹果"},
+	}
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	for _, test := range tests {
+
+		cdq, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL", Name: test.company}, fixtureDate(t), test.buffer)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if cdq.Name != test.expected {
+			t.Errorf("%s: Name = %q, want %q", test.name, cdq.Name, test.expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	// 不设置客户端，解析不能访问网络
+	var yahoo YahooFinance
+	cdq, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL", Name: "Apple"}, fixtureDate(t), readFixture(t, "chart_1m.json"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// 价格按float32乘100后截断为分，152.9会变成15289
+	tests := []struct {
+		name     string
+		series   market.QuoteSeries
+		expected []market.Bar
+	}{
+		{"pre", cdq.Pre, []market.Bar{
+			{Timestamp: 1496322000, Open: 15280, Close: 15285, Max: 15289, Min: 15275, Volume: 1200},
+		}},
+		{"regular", cdq.Regular, []market.Bar{
+			{Timestamp: 1496323800, Open: 15317, Close: 15291, Max: 15320, Min: 15280, Volume: 901234},
+			{Timestamp: 1496323860, Open: 15289, Close: 15256, Max: 15295, Min: 15250, Volume: 252100},
+			{Timestamp: 1496323920, Open: 15255, Close: 15262, Max: 15270, Min: 15250, Volume: 198300},
+		}},
+		{"post", cdq.Post, []market.Bar{
+			{Timestamp: 1496347200, Open: 15320, Close: 15310, Max: 15325, Min: 15305, Volume: 45000},
+		}},
+	}
+
+	for _, test := range tests {
+
+		if int(test.series.Count) != len(test.expected) {
+			t.Errorf("%s: %d bars, want %d", test.name, test.series.Count, len(test.expected))
+			continue
+		}
+
+		for index, expected := range test.expected {
+			if bar := test.series.Bar(index); bar != expected {
+				t.Errorf("%s[%d] = %+v, want %+v", test.name, index, bar, expected)
+			}
+		}
+	}
+}
+
+func TestYahooFinanceConfigDefaults(t *testing.T) {
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+
+	if yahoo.ParallelMax(market.America{}) != defaultYahooParallelMax || yahoo.RetryCount() != defaultYahooRetryCount || yahoo.RetryInterval() != defaultYahooRetryInterval {
+		t.Errorf("defaults = %d/%d/%s, want %d/%d/%s", yahoo.ParallelMax(market.America{}), yahoo.RetryCount(), yahoo.RetryInterval(),
+			defaultYahooParallelMax, defaultYahooRetryCount, defaultYahooRetryInterval)
+	}
+
+	if yahoo.config.Timeout != defaultYahooTimeout || yahoo.config.IdleConnTimeout != defaultYahooIdleTimeout || yahoo.config.MaxIdleConnsPerHost != defaultYahooParallelMax {
+		t.Errorf("connection defaults = %s/%s/%d", yahoo.config.Timeout, yahoo.config.IdleConnTimeout, yahoo.config.MaxIdleConnsPerHost)
+	}
+}
+
+func TestMissingQuoteArray(t *testing.T) {
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	company := market.Company{Code: "AAPL"}
+
+	// open整个数组为null时指明缺失的数组
+	_, err := yahoo.Parse(market.America{}, company, fixtureDate(t), readFixture(t, "chart_1m_null_open.json"))
+	if err == nil || !strings.Contains(err.Error(), "open") {
+		t.Errorf("Parse with a null open array: err = %v, want it to name open", err)
+	}
+
+	// 数组存在但长度不一致
+	short := patchFixture(t, readFixture(t, "chart_1m.json"), `"high": [152.9, 153.2, 152.95, 152.7, 153.25]`, `"high": [152.9, 153.2]`)
+	_, err = yahoo.Parse(market.America{}, company, fixtureDate(t), short)
+	if err == nil || !strings.Contains(err.Error(), "Quotes数量不正确") {
+		t.Errorf("Parse with a short high array: err = %v, want a length mismatch", err)
+	}
+}
+
+func TestTradingSessions(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	withoutZone := patchFixture(t, buffer, `"exchangeTimezoneName": "America/New_York",`, ``)
+
+	for name, buffer := range map[string][]byte{"zone name": buffer, "offset only": withoutZone} {
+
+		quote := &YahooQuote{}
+		if err := json.Unmarshal(buffer, quote); err != nil {
+			t.Fatal(err)
+		}
+
+		sessions, err := quote.TradingSessions()
+		if err != nil {
+			t.Fatalf("%s: TradingSessions: %v", name, err)
+		}
+
+		// 交易所所在时区的时间
+		tests := []struct {
+			session    market.Session
+			start, end string
+		}{
+			{sessions.Pre, "04:00", "09:30"},
+			{sessions.Regular, "09:30", "16:00"},
+			{sessions.Post, "16:00", "20:00"},
+		}
+
+		for _, test := range tests {
+			if test.session.Start.Format("15:04") != test.start || test.session.End.Format("15:04") != test.end {
+				t.Errorf("%s: session = %s-%s, want %s-%s", name, test.session.Start.Format("15:04"), test.session.End.Format("15:04"), test.start, test.end)
+			}
+		}
+
+		if sessions.Regular.Duration() != 390*time.Minute {
+			t.Errorf("%s: regular session lasts %s, want 6h30m", name, sessions.Regular.Duration())
+		}
+	}
+
+	// 缺少交易时段
+	quote := &YahooQuote{}
+	json.Unmarshal(patchFixture(t, buffer, `"regular": [[{"timezone": "EDT", "start": 1496323800, "end": 1496347200, "gmtoffset": -14400}]]`, `"regular": []`), quote)
+	if _, err := quote.TradingSessions(); err == nil {
+		t.Error("TradingSessions without regular periods should fail")
+	}
+}
+
+func TestParseFile(t *testing.T) {
+
+	var yahoo YahooFinance
+	company := market.Company{Code: "AAPL"}
+
+	// 与直接解析内容的结果相同
+	expected, err := yahoo.Parse(market.America{}, company, fixtureDate(t), readFixture(t, "chart_1m.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := yahoo.ParseFile(filepath.Join("testdata", "chart_1m.json"), market.America{}, company, fixtureDate(t))
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+
+	if err = actual.Equal(*expected); err != nil {
+		t.Errorf("ParseFile differs from Parse: %v", err)
+	}
+
+	if _, err = yahoo.ParseFile(filepath.Join("testdata", "missing.json"), market.America{}, company, fixtureDate(t)); err == nil {
+		t.Error("ParseFile of a missing capture should fail")
+	}
+}
+
+func TestValidateSession(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	regular := `"regular": [[{"timezone": "EDT", "start": 1496323800, "end": 1496347200,`
+	withEnd := func(end string) []byte {
+		return patchFixture(t, buffer, regular, `"regular": [[{"timezone": "EDT", "start": 1496323800, "end": `+end+`,`)
+	}
+
+	crypto := patchFixture(t, withEnd("1496410200"), `"instrumentType": "EQUITY"`, `"instrumentType": "CRYPTOCURRENCY"`)
+
+	tests := []struct {
+		name   string
+		buffer []byte
+		valid  bool
+	}{
+		{"6.5 hours", buffer, true},
+		{"0 minutes", withEnd("1496323800"), false},
+		{"40 hours", withEnd("1496467800"), false},
+		// 24小时的交易时段对股票不合理，对加密货币合理
+		{"24 hours equity", withEnd("1496410200"), false},
+		{"24 hours crypto", crypto, true},
+	}
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	for _, test := range tests {
+
+		quote := &YahooQuote{}
+		if err := json.Unmarshal(test.buffer, quote); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if err := quote.ValidateSession(); (err == nil) != test.valid {
+			t.Errorf("%s: ValidateSession = %v, want valid %v", test.name, err, test.valid)
+		}
+
+		// 不合理的交易时段在校验时拒绝
+		if err := yahoo.valid(quote); (err == nil) != test.valid {
+			t.Errorf("%s: valid = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestTimezoneMismatch(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	offset := `"gmtoffset": -14400,
+          "timezone": "EDT",`
+
+	tests := []struct {
+		name     string
+		buffer   []byte
+		mismatch bool
+		delta    time.Duration
+	}{
+		{"consistent", buffer, false, 0},
+		// 夏令时前的旧偏移只差1小时，不算不一致
+		{"stale dst", patchFixture(t, buffer, offset, `"gmtoffset": -18000, "timezone": "EST",`), false, 0},
+		{"wrong zone", patchFixture(t, buffer, offset, `"gmtoffset": 28800, "timezone": "CST",`), true, -12 * time.Hour},
+		{"no timezone name", patchFixture(t, patchFixture(t, buffer, offset, `"gmtoffset": 28800, "timezone": "CST",`), `"exchangeTimezoneName": "America/New_York",`, ``), false, 0},
+	}
+
+	for _, test := range tests {
+
+		quote := &YahooQuote{}
+		if err := json.Unmarshal(test.buffer, quote); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		delta, mismatch := quote.TimezoneMismatch()
+		if mismatch != test.mismatch || delta != test.delta {
+			t.Errorf("%s: TimezoneMismatch = %s, %v, want %s, %v", test.name, delta, mismatch, test.delta, test.mismatch)
+		}
+	}
+
+	// 不一致时仅提示，仍然解析
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	if _, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL"}, fixtureDate(t), tests[2].buffer); err != nil {
+		t.Errorf("Parse with a mismatched offset = %v, want it parsed", err)
+	}
+}
+
+func TestNonTradingDay(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	crypto := patchFixture(t, buffer, `"instrumentType": "EQUITY"`, `"instrumentType": "CRYPTOCURRENCY"`)
+	empty := patchFixture(t, buffer, `"timestamp": [1496322000, 1496323800, 1496323860, 1496323920, 1496347200],`, `"timestamp": [],`)
+	empty = patchFixture(t, empty, `"open": [152.8, 153.17, 152.9, 152.55, 153.2],
+              "close": [152.85, 152.91, 152.56, 152.62, 153.1],
+              "high": [152.9, 153.2, 152.95, 152.7, 153.25],
+              "low": [152.75, 152.8, 152.5, 152.5, 153.05],
+              "volume": [1200, 901234, 252100, 198300, 45000]`, `"open": [], "close": [], "high": [], "low": [], "volume": []`)
+
+	// 2017-06-03为周六
+	saturday := fixtureDate(t).AddDate(0, 0, 2)
+
+	tests := []struct {
+		name     string
+		buffer   []byte
+		date     time.Time
+		expected error
+	}{
+		{"weekday", buffer, fixtureDate(t), nil},
+		{"saturday", buffer, saturday, ErrNonTradingDay},
+		{"sunday", buffer, saturday.AddDate(0, 0, 1), ErrNonTradingDay},
+		// 加密货币周末也交易
+		{"crypto saturday", crypto, saturday, nil},
+		{"empty saturday", empty, saturday, nil},
+	}
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	for _, test := range tests {
+		_, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL"}, test.date, test.buffer)
+		if err != test.expected {
+			t.Errorf("%s: Parse = %v, want %v", test.name, err, test.expected)
+		}
+	}
+}
+
+func TestMisalignedClose(t *testing.T) {
+
+	buffer := fullDayFixture(t)
+
+	// 收盘价数组整体前移一位
+	var value map[string]interface{}
+	if err := json.Unmarshal(buffer, &value); err != nil {
+		t.Fatal(err)
+	}
+
+	quote := value["chart"].(map[string]interface{})["result"].([]interface{})[0].(map[string]interface{})["indicators"].(map[string]interface{})["quote"].([]interface{})[0].(map[string]interface{})
+	closes := quote["close"].([]interface{})
+	quote["close"] = append(closes[1:], closes[len(closes)-1])
+
+	shifted, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	company := market.Company{Code: "AAPL"}
+	if _, err := yahoo.Parse(market.America{}, company, fixtureDate(t), buffer); err != nil {
+		t.Fatalf("Parse of the aligned day: %v", err)
+	}
+
+	if _, err := yahoo.Parse(market.America{}, company, fixtureDate(t), shifted); err == nil {
+		t.Error("Parse of a shifted close array should fail")
+	}
+
+	// 报价太少时不判断，偶尔的越界不算错位
+	tests := []struct {
+		name      string
+		closes    []float32
+		misplaced bool
+	}{
+		{"few bars", []float32{5, 5, 5}, false},
+		{"one outside", []float32{1.5, 1.5, 1.5, 1.5, 1.5, 1.5, 1.5, 1.5, 1.5, 5}, false},
+		{"most outside", []float32{5, 5, 5, 5, 5, 5, 1.5, 1.5, 1.5, 1.5}, true},
+		// 空报价不计入
+		{"empty bars", []float32{5, 5, 5, 5, 5, 5, 0, 0, 0, 0, 1.5, 1.5, 1.5, 1.5}, true},
+	}
+
+	for _, test := range tests {
+
+		highs, lows := make([]float32, len(test.closes)), make([]float32, len(test.closes))
+		for index := range test.closes {
+			highs[index], lows[index] = 2, 1
+		}
+
+		if misplaced := yahoo.misaligned(test.closes, highs, lows); misplaced != test.misplaced {
+			t.Errorf("%s: misaligned = %v, want %v", test.name, misplaced, test.misplaced)
+		}
+	}
+}
+
+func TestMarketParallelMax(t *testing.T) {
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{
+		ParallelMax:       16,
+		MarketParallelMax: map[string]int{"america": 32, "China": 4, "HongKong": 0},
+	})
+
+	tests := []struct {
+		market   market.Market
+		expected int
+	}{
+		{market.America{}, 32},
+		// 市场名称不区分大小写
+		{market.China{}, 4},
+		// 未设置或不大于0时使用ParallelMax
+		{market.HongKong{}, 16},
+	}
+
+	for _, test := range tests {
+		if parallel := yahoo.ParallelMax(test.market); parallel != test.expected {
+			t.Errorf("ParallelMax(%s) = %d, want %d", test.market.Name(), parallel, test.expected)
+		}
+	}
+}
+
+func TestTimezoneFromQueryCode(t *testing.T) {
+
+	// 没有IANA时区名称时根据查询代码后缀判断
+	buffer := patchFixture(t, readFixture(t, "chart_1m.json"), `"exchangeTimezoneName": "America/New_York",`, ``)
+	hongkong := patchFixture(t, buffer, `"symbol": "AAPL",`, `"symbol": "0700.HK",`)
+
+	tests := []struct {
+		name     string
+		buffer   []byte
+		expected string
+	}{
+		{"suffix", hongkong, "Asia/Hong_Kong"},
+		// 没有后缀时使用时区缩写
+		{"no suffix", buffer, "EDT"},
+	}
+
+	for _, test := range tests {
+
+		quote := &YahooQuote{}
+		if err := json.Unmarshal(test.buffer, quote); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		sessions, err := quote.TradingSessions()
+		if err != nil {
+			t.Fatalf("%s: TradingSessions: %v", test.name, err)
+		}
+
+		if location := sessions.Regular.Start.Location().String(); location != test.expected {
+			t.Errorf("%s: session location = %s, want %s", test.name, location, test.expected)
+		}
+	}
+}
+
+func TestMillisecondTimestamps(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m.json")
+	millis := patchFixture(t, buffer,
+		`"timestamp": [1496322000, 1496323800, 1496323860, 1496323920, 1496347200],`,
+		`"timestamp": [1496322000000, 1496323800000, 1496323860000, 1496323920000, 1496347200000],`)
+	millis = patchFixture(t, millis,
+		`"regular": [[{"timezone": "EDT", "start": 1496323800, "end": 1496347200,`,
+		`"regular": [[{"timezone": "EDT", "start": 1496323800000, "end": 1496347200000,`)
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	company := market.Company{Code: "AAPL"}
+
+	expected, err := yahoo.Parse(market.America{}, company, fixtureDate(t), buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cdq, err := yahoo.Parse(market.America{}, company, fixtureDate(t), millis)
+	if err != nil {
+		t.Fatalf("Parse with millisecond timestamps: %v", err)
+	}
+
+	// 第一个正常交易时段报价为2017-06-01 09:30 纽约时间
+	if cdq.Regular.Count == 0 {
+		t.Fatal("no regular bars parsed from millisecond timestamps")
+	}
+
+	first := time.Unix(int64(cdq.Regular.Timestamp[0]), 0).In(fixtureDate(t).Location())
+	if first.Format("2006-01-02 15:04") != "2017-06-01 09:30" {
+		t.Errorf("first regular bar at %s, want 2017-06-01 09:30", first)
+	}
+
+	for _, session := range []struct {
+		name             string
+		actual, expected market.QuoteSeries
+	}{
+		{"pre", cdq.Pre, expected.Pre},
+		{"regular", cdq.Regular, expected.Regular},
+		{"post", cdq.Post, expected.Post},
+	} {
+		if !reflect.DeepEqual(session.actual, session.expected) {
+			t.Errorf("%s = %+v, want %+v", session.name, session.actual, session.expected)
+		}
+	}
+}
+
+func TestSecondsTimestamp(t *testing.T) {
+
+	tests := []struct {
+		ts       int64
+		expected int64
+	}{
+		{0, 0},
+		{1496323800, 1496323800},
+		{1496323800000, 1496323800},
+		{maxSecondsTimestamp, maxSecondsTimestamp},
+		{-1496323800000, -1496323800},
+	}
+
+	for _, test := range tests {
+		if seconds := secondsTimestamp(test.ts); seconds != test.expected {
+			t.Errorf("secondsTimestamp(%d) = %d, want %d", test.ts, seconds, test.expected)
+		}
+	}
+}
+
+func TestNullVolume(t *testing.T) {
+
+	buffer := readFixture(t, "chart_1m_null_volume.json")
+
+	quote := &YahooQuote{}
+	if err := json.Unmarshal(buffer, quote); err != nil {
+		t.Fatal(err)
+	}
+
+	if !quote.FillMissingVolume() {
+		t.Error("FillMissingVolume = false for a null volume array")
+	}
+
+	// 已经填充过不再填充
+	if quote.FillMissingVolume() {
+		t.Error("FillMissingVolume filled a volume array twice")
+	}
+
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	company := market.Company{Code: "AAPL"}
+
+	expected, err := yahoo.Parse(market.America{}, company, fixtureDate(t), readFixture(t, "chart_1m.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cdq, err := yahoo.Parse(market.America{}, company, fixtureDate(t), buffer)
+	if err != nil {
+		t.Fatalf("Parse with null volume: %v", err)
+	}
+
+	// 价格不变，成交量为0
+	if cdq.Regular.Count != expected.Regular.Count || !reflect.DeepEqual(cdq.Regular.Close, expected.Regular.Close) {
+		t.Errorf("regular = %+v, want the prices of %+v", cdq.Regular, expected.Regular)
+	}
+
+	for _, series := range []market.QuoteSeries{cdq.Pre, cdq.Regular, cdq.Post} {
+		for index, volume := range series.Volume {
+			if volume != 0 {
+				t.Errorf("volume %d = %d, want 0", index, volume)
+			}
+		}
+	}
+
+	// 成交量数组存在但长度不对时仍然拒绝
+	short := patchFixture(t, buffer, `"volume": null`, `"volume": []`)
+	if _, err = yahoo.Parse(market.America{}, company, fixtureDate(t), short); err == nil {
+		t.Error("Parse with an empty volume array should fail")
+	}
+}
+
+func TestMisalignedTimestamps(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		buffer   []byte
+		expected int
+	}{
+		{"aligned", readFixture(t, "chart_1m.json"), 0},
+		// 9:31:27和9:32:05不在整分钟上
+		{"off grid", readFixture(t, "chart_1m_off_grid.json"), 2},
+		// 没有dataGranularity时同样按1分钟
+		{"no granularity", patchFixture(t, readFixture(t, "chart_1m.json"), `"dataGranularity": "1m"`, `"dataGranularity": ""`), 0},
+		{"off grid without granularity", patchFixture(t, readFixture(t, "chart_1m_off_grid.json"), `"dataGranularity": "1m"`, `"dataGranularity": ""`), 2},
+	}
+
+	for _, test := range tests {
+
+		quote := &YahooQuote{}
+		if err := json.Unmarshal(test.buffer, quote); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if count := quote.MisalignedTimestamps(); count != test.expected {
+			t.Errorf("%s: MisalignedTimestamps = %d, want %d", test.name, count, test.expected)
+		}
+	}
+
+	// 不在网格上的报价仅提示，仍然解析
+	yahoo := NewYahooFinance(YahooFinanceConfig{})
+	if _, err := yahoo.Parse(market.America{}, market.Company{Code: "AAPL"}, fixtureDate(t), readFixture(t, "chart_1m_off_grid.json")); err != nil {
+		t.Errorf("Parse of off-grid timestamps = %v, want it parsed", err)
+	}
+}
+
+func TestCrawlFutureDate(t *testing.T) {
+
+	attempts := new(attemptLog)
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.add()
+		w.Write(readFixture(t, "chart_1m.json"))
+	}))
+	defer server.Close()
+
+	location, err := time.LoadLocation(market.America{}.Timezone())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().In(location)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+
+	for _, date := range []time.Time{tomorrow, tomorrow.AddDate(0, 0, 30)} {
+		if _, err := yahoo.Crawl(market.America{}, market.Company{Code: "AAPL"}, date); err != ErrFutureDate {
+			t.Errorf("Crawl(%s) = %v, want ErrFutureDate", date.Format("20060102"), err)
+		}
+	}
+
+	// 不发出请求
+	if attempts.count() != 0 {
+		t.Errorf("server got %d requests for future dates, want 0", attempts.count())
+	}
+}
+
+func TestCrawlBeforeOpen(t *testing.T) {
+
+	attempts := new(attemptLog)
+	yahoo, server := newTestYahooFinance(YahooFinanceConfig{RetryCount: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.add()
+		w.Write(readFixture(t, "chart_1m.json"))
+	}))
+	defer server.Close()
+
+	// 当天盘前还没有正常交易时段的报价，开盘后才抓取
+	date := fixtureDate(t)
+	tests := []struct {
+		now      time.Time
+		requests int
+		err      error
+	}{
+		{date.Add(time.Hour), 0, ErrFutureDate},
+		{date.Add(9*time.Hour + 29*time.Minute), 0, ErrFutureDate},
+		{date.Add(9*time.Hour + 30*time.Minute), 0, ErrFutureDate},
+		{date.Add(9*time.Hour + 31*time.Minute), 1, nil},
+	}
+
+	for _, test := range tests {
+
+		now := test.now
+		yahoo.now = func() time.Time { return now }
+
+		before := attempts.count()
+		_, err := yahoo.Crawl(market.America{}, market.Company{Code: "AAPL"}, date)
+		if err != test.err {
+			t.Errorf("Crawl at %s = %v, want %v", now.Format("15:04"), err, test.err)
+		}
+
+		if requests := attempts.count() - before; requests != test.requests {
+			t.Errorf("Crawl at %s sent %d requests, want %d", now.Format("15:04"), requests, test.requests)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// AlignGrid 将多个公司某天的分时报价对齐到同一时间网格
+// 网格为所有公司报价时间的并集，每个公司的报价与网格一一对应，缺失处为nil，当天没有记录的公司全部为nil
+func AlignGrid(s Store, _market market.Market, codes []string, date time.Time) (map[string][]*market.Bar, []time.Time, error) {
+
+	quote, err := s.Load(_market, date)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 各公司按时间索引的报价
+	byCode := make(map[string]map[uint32]market.Bar, len(codes))
+	timestamps := make(map[uint32]bool)
+	for _, code := range codes {
+
+		bars := make(map[uint32]market.Bar)
+		byCode[code] = bars
+
+		cdq, found := quote.Find(code)
+		if !found {
+			continue
+		}
+
+		for _, series := range []market.QuoteSeries{cdq.Pre, cdq.Regular, cdq.Post} {
+			for index := 0; index < int(series.Count); index++ {
+				bar := series.Bar(index)
+				bars[bar.Timestamp] = bar
+				timestamps[bar.Timestamp] = true
+			}
+		}
+	}
+
+	sorted := make([]uint32, 0, len(timestamps))
+	for timestamp := range timestamps {
+		sorted = append(sorted, timestamp)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	grid := make([]time.Time, len(sorted))
+	for index, timestamp := range sorted {
+		grid[index] = time.Unix(int64(timestamp), 0).In(date.Location())
+	}
+
+	aligned := make(map[string][]*market.Bar, len(codes))
+	for code, bars := range byCode {
+
+		row := make([]*market.Bar, len(sorted))
+		for index, timestamp := range sorted {
+			if bar, found := bars[timestamp]; found {
+				row[index] = &bar
+			}
+		}
+
+		aligned[code] = row
+	}
+
+	return aligned, grid, nil
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// minuteSeries 从date的hour:minute起，每分钟一个收盘价为closes的报价
+func minuteSeries(date time.Time, hour, minute int, closes ...uint32) market.QuoteSeries {
+
+	start := date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	series := market.QuoteSeries{Count: uint32(len(closes))}
+	for index, close := range closes {
+		series.Timestamp = append(series.Timestamp, uint32(start.Add(time.Duration(index)*time.Minute).Unix()))
+		series.Open = append(series.Open, close)
+		series.Close = append(series.Close, close)
+		series.Max = append(series.Max, close)
+		series.Min = append(series.Min, close)
+		series.Volume = append(series.Volume, 100)
+	}
+
+	return series
+}
+
+func TestAlignGrid(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	date := testDate(t, 6, 1)
+
+	// A在9:30至9:32，B在9:31至9:33，时间部分重叠
+	saveDay(t, s, date,
+		market.CompanyDailyQuote{Company: market.Company{Code: "A"}, Regular: minuteSeries(date, 9, 30, 100, 101, 102)},
+		market.CompanyDailyQuote{Company: market.Company{Code: "B"}, Regular: minuteSeries(date, 9, 31, 200, 201, 202)},
+	)
+
+	aligned, grid, err := AlignGrid(s, market.America{}, []string{"A", "B", "Z"}, date)
+	if err != nil {
+		t.Fatalf("AlignGrid: %v", err)
+	}
+
+	expectedGrid := []string{"09:30", "09:31", "09:32", "09:33"}
+	if len(grid) != len(expectedGrid) {
+		t.Fatalf("grid = %v, want %v", grid, expectedGrid)
+	}
+
+	for index, minute := range expectedGrid {
+		if grid[index].Format("15:04") != minute || grid[index].Location() != date.Location() {
+			t.Errorf("grid[%d] = %s, want %s in the market timezone", index, grid[index], minute)
+		}
+	}
+
+	// 0为缺失
+	expected := map[string][]uint32{
+		"A": {100, 101, 102, 0},
+		"B": {0, 200, 201, 202},
+		"Z": {0, 0, 0, 0},
+	}
+
+	if len(aligned) != len(expected) {
+		t.Errorf("aligned %d companies, want %d", len(aligned), len(expected))
+	}
+
+	for code, closes := range expected {
+
+		row := aligned[code]
+		if len(row) != len(grid) {
+			t.Errorf("%s has %d cells, want one per grid point", code, len(row))
+			continue
+		}
+
+		for index, close := range closes {
+			switch {
+			case close == 0 && row[index] != nil:
+				t.Errorf("%s at %s = %+v, want nil", code, expectedGrid[index], *row[index])
+			case close != 0 && (row[index] == nil || row[index].Close != close):
+				t.Errorf("%s at %s = %v, want close %d", code, expectedGrid[index], row[index], close)
+			}
+		}
+	}
+
+	if _, _, err = AlignGrid(s, market.America{}, []string{"A"}, testDate(t, 6, 2)); err == nil {
+		t.Error("AlignGrid of a day that was never recorded should fail")
+	}
+}
@@ -105,3 +105,8 @@ func (s AliyunOSS) Load(_market market.Market, date time.Time) (market.DailyQuot
 
 	return mdq, nil
 }
+
+// Delete 删除
+func (s AliyunOSS) Delete(_market market.Market, date time.Time) error {
+	return s.bucket.DeleteObject(s.objectKey(_market, date))
+}
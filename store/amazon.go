@@ -137,3 +137,14 @@ func (s AmazonS3) Load(_market market.Market, date time.Time) (market.DailyQuote
 
 	return mdq, nil
 }
+
+// Delete 删除
+func (s AmazonS3) Delete(_market market.Market, date time.Time) error {
+
+	_, err := s.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.savePath(_market, date)),
+	})
+
+	return err
+}
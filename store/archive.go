@@ -0,0 +1,231 @@
+package store
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+const (
+	// archiveManifestName 归档清单文件名
+	archiveManifestName = "manifest.json"
+)
+
+var (
+	// ErrArchiveManifestMissing 归档中没有清单
+	ErrArchiveManifestMissing = errors.New("归档中没有清单")
+)
+
+// ArchiveManifest 归档清单
+type ArchiveManifest struct {
+	Market string                 `json:"market"` // 市场
+	From   string                 `json:"from"`   // 起始日期(含)
+	To     string                 `json:"to"`     // 结束日期(不含)
+	Days   []ArchiveManifestEntry `json:"days"`   // 每日记录
+}
+
+// ArchiveManifestEntry 归档清单中的每日记录
+type ArchiveManifestEntry struct {
+	Name      string `json:"name"`      // 归档内的文件名
+	Date      string `json:"date"`      // 日期
+	Companies int    `json:"companies"` // 上市公司数
+	Size      int    `json:"size"`      // 字节数
+	SHA1      string `json:"sha1"`      // 校验和
+}
+
+// archiveEntryName 归档内的文件名
+func archiveEntryName(_market market.Market, date time.Time) string {
+	return fmt.Sprintf("%s/%s.mdq", strings.ToLower(_market.Name()), date.Format("20060102"))
+}
+
+// ExportArchive 将市场在[from, to)之间的报价导出为tar.gz归档
+func ExportArchive(w io.Writer, s Store, _market market.Market, from, to time.Time) error {
+
+	zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+
+	manifest := ArchiveManifest{
+		Market: _market.Name(),
+		From:   from.Format("20060102"),
+		To:     to.Format("20060102"),
+	}
+
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+
+		exists, err := s.Exists(_market, date)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		quote, err := s.Load(_market, date)
+		if err != nil {
+			return err
+		}
+
+		buffer := quote.Marshal()
+		checksum := sha1.Sum(buffer)
+		name := archiveEntryName(_market, date)
+
+		err = writeArchiveEntry(tw, name, date, buffer)
+		if err != nil {
+			return err
+		}
+
+		manifest.Days = append(manifest.Days, ArchiveManifestEntry{
+			Name:      name,
+			Date:      date.Format("20060102"),
+			Companies: len(quote.Quotes),
+			Size:      len(buffer),
+			SHA1:      hex.EncodeToString(checksum[:]),
+		})
+	}
+
+	// 清单放在最后，包含所有记录的校验和
+	buffer, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = writeArchiveEntry(tw, archiveManifestName, time.Now(), buffer)
+	if err != nil {
+		return err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeArchiveEntry 写入归档文件
+func writeArchiveEntry(tw *tar.Writer, name string, modTime time.Time, buffer []byte) error {
+
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(buffer)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(buffer)
+	return err
+}
+
+// ImportArchive 将ExportArchive导出的归档校验后导入存储
+func ImportArchive(r io.Reader, s Store) error {
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+
+	var manifest *ArchiveManifest
+	entries := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		buffer, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		if header.Name == archiveManifestName {
+			manifest = new(ArchiveManifest)
+			err = json.Unmarshal(buffer, manifest)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		entries[path.Clean(header.Name)] = buffer
+	}
+
+	if manifest == nil {
+		return ErrArchiveManifestMissing
+	}
+
+	_market, err := market.Get(manifest.Market)
+	if err != nil {
+		return err
+	}
+
+	// 先全部校验和解码，避免导入一半
+	quotes := make([]market.DailyQuote, 0, len(manifest.Days))
+	for _, day := range manifest.Days {
+
+		buffer, found := entries[day.Name]
+		if !found {
+			return fmt.Errorf("归档中缺少%s", day.Name)
+		}
+
+		checksum := sha1.Sum(buffer)
+		if hex.EncodeToString(checksum[:]) != day.SHA1 {
+			return fmt.Errorf("%s校验和不正确", day.Name)
+		}
+
+		quote, err := decodeArchiveEntry(_market, day.Name, buffer)
+		if err != nil {
+			return err
+		}
+		quotes = append(quotes, quote)
+	}
+
+	for _, quote := range quotes {
+
+		err = s.Save(quote)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeArchiveEntry 解码归档中的一天，校验和只能说明与清单一致，截断或伪造的数据会让Unmarshal越界，转为错误返回
+// 限制容量，越过数据末尾的切片也会越界，不会读到缓冲区中剩余的字节
+func decodeArchiveEntry(_market market.Market, name string, buffer []byte) (quote market.DailyQuote, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s格式不正确: %v", name, r)
+		}
+	}()
+
+	quote = market.DailyQuote{Market: _market}
+	quote.Unmarshal(buffer[:len(buffer):len(buffer)])
+
+	return quote, nil
+}
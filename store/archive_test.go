@@ -0,0 +1,170 @@
+package store
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+
+	source := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, source)
+
+	var buffer bytes.Buffer
+	err := ExportArchive(&buffer, source, market.America{}, testDate(t, 6, 1), testDate(t, 6, 9))
+	if err != nil {
+		t.Fatalf("ExportArchive: %v", err)
+	}
+
+	target := newTestFileSystem(t, FileSystemConfig{})
+	err = ImportArchive(bytes.NewReader(buffer.Bytes()), target)
+	if err != nil {
+		t.Fatalf("ImportArchive: %v", err)
+	}
+
+	for date := testDate(t, 6, 1); date.Before(testDate(t, 6, 9)); date = date.AddDate(0, 0, 1) {
+
+		exists, _ := source.Exists(market.America{}, date)
+		imported, _ := target.Exists(market.America{}, date)
+		if exists != imported {
+			t.Errorf("%s: exported %v, imported %v", date.Format("0102"), exists, imported)
+		}
+
+		if !exists {
+			continue
+		}
+
+		expected, err := source.Load(market.America{}, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := target.Load(market.America{}, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = actual.Equal(expected); err != nil {
+			t.Errorf("%s: %v", date.Format("0102"), err)
+		}
+	}
+}
+
+func TestImportArchiveRejectsTampering(t *testing.T) {
+
+	day := testDate(t, 6, 1)
+	quote := market.DailyQuote{
+		Market: market.America{},
+		Date:   day,
+		Quotes: []market.CompanyDailyQuote{{Company: market.Company{Code: "A"}, Regular: oneBar(day, 100, 100)}},
+	}
+	name := archiveEntryName(market.America{}, day)
+
+	// archive 按清单生成归档，manifest为nil时不写清单
+	archive := func(manifest *ArchiveManifest) []byte {
+
+		var buffer bytes.Buffer
+		zw := gzip.NewWriter(&buffer)
+		tw := tar.NewWriter(zw)
+
+		if err := writeArchiveEntry(tw, name, day, quote.Marshal()); err != nil {
+			t.Fatal(err)
+		}
+
+		if manifest != nil {
+			content, err := json.Marshal(manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = writeArchiveEntry(tw, archiveManifestName, day, content); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tw.Close()
+		zw.Close()
+
+		return buffer.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		manifest *ArchiveManifest
+	}{
+		{"missing manifest", nil},
+		{"wrong checksum", &ArchiveManifest{Market: "America", Days: []ArchiveManifestEntry{{Name: name, SHA1: "0000"}}}},
+		{"missing entry", &ArchiveManifest{Market: "America", Days: []ArchiveManifestEntry{{Name: "america/20170602.mdq"}}}},
+		{"unknown market", &ArchiveManifest{Market: "Mars"}},
+	}
+
+	for _, test := range tests {
+
+		target := newTestFileSystem(t, FileSystemConfig{})
+		if err := ImportArchive(bytes.NewReader(archive(test.manifest)), target); err == nil {
+			t.Errorf("%s: ImportArchive should fail", test.name)
+		}
+
+		// 校验失败时什么都不写
+		if exists, _ := target.Exists(market.America{}, day); exists {
+			t.Errorf("%s: a rejected archive should not write any day", test.name)
+		}
+	}
+
+	if err := ImportArchive(bytes.NewReader([]byte("not an archive")), newTestFileSystem(t, FileSystemConfig{})); err == nil {
+		t.Error("ImportArchive of garbage should fail")
+	}
+}
+
+func TestImportArchiveTruncatedEntry(t *testing.T) {
+
+	day := testDate(t, 6, 1)
+	quote := market.DailyQuote{
+		Market: market.America{},
+		Date:   day,
+		Quotes: []market.CompanyDailyQuote{{Company: market.Company{Code: "A"}, Regular: oneBar(day, 100, 100)}},
+	}
+	name := archiveEntryName(market.America{}, day)
+	full := quote.Marshal()
+
+	// 清单的校验和与截断后的数据一致，只能在解码时发现
+	for _, size := range []int{0, 8, 14, len(full) - 1} {
+
+		entry := full[:size]
+		checksum := sha1.Sum(entry)
+		manifest, err := json.Marshal(ArchiveManifest{Market: "America", Days: []ArchiveManifestEntry{{Name: name, SHA1: hex.EncodeToString(checksum[:])}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buffer bytes.Buffer
+		zw := gzip.NewWriter(&buffer)
+		tw := tar.NewWriter(zw)
+		if err = writeArchiveEntry(tw, name, day, entry); err != nil {
+			t.Fatal(err)
+		}
+		if err = writeArchiveEntry(tw, archiveManifestName, day, manifest); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		zw.Close()
+
+		target := newTestFileSystem(t, FileSystemConfig{})
+		err = ImportArchive(bytes.NewReader(buffer.Bytes()), target)
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("ImportArchive of %d/%d bytes = %v, want an error naming %s", size, len(full), err, name)
+		}
+
+		if exists, _ := target.Exists(market.America{}, day); exists {
+			t.Errorf("ImportArchive of %d/%d bytes wrote the day", size, len(full))
+		}
+	}
+}
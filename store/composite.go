@@ -0,0 +1,73 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// CompositeStore 按优先级组合的存储，读取时依次查找，写入只写第一个存储，用于迁移期间同时读取新旧存储
+type CompositeStore struct {
+	stores []Store
+}
+
+// NewCompositeStore 新建组合存储，primary为写入的存储，fallbacks按优先级排列
+func NewCompositeStore(primary Store, fallbacks ...Store) *CompositeStore {
+	return &CompositeStore{stores: append([]Store{primary}, fallbacks...)}
+}
+
+// Exists 任一存储记录过即为记录过
+func (s CompositeStore) Exists(_market market.Market, date time.Time) (bool, error) {
+
+	for _, store := range s.stores {
+
+		exists, err := store.Exists(_market, date)
+		if err != nil {
+			return false, err
+		}
+
+		if exists {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Save 保存到主存储
+func (s CompositeStore) Save(quote market.DailyQuote) error {
+	return s.stores[0].Save(quote)
+}
+
+// Load 从第一个记录过的存储读取
+func (s CompositeStore) Load(_market market.Market, date time.Time) (market.DailyQuote, error) {
+
+	for _, store := range s.stores {
+
+		exists, err := store.Exists(_market, date)
+		if err != nil {
+			return market.DailyQuote{}, err
+		}
+
+		if exists {
+			return store.Load(_market, date)
+		}
+	}
+
+	return market.DailyQuote{}, fmt.Errorf("[%s] %s的报价不存在", _market.Name(), date.Format("20060102"))
+}
+
+// Delete 从所有存储删除，避免删除后又从后备存储读到
+func (s CompositeStore) Delete(_market market.Market, date time.Time) error {
+
+	for _, store := range s.stores {
+
+		err := store.Delete(_market, date)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// closeOf 存储中公司某天的收盘价
+func closeOf(t *testing.T, s Store, day int, code string) uint32 {
+	t.Helper()
+
+	quote, err := s.Load(market.America{}, testDate(t, 6, day))
+	if err != nil {
+		t.Fatalf("Load 6/%d: %v", day, err)
+	}
+
+	cdq, found := quote.Find(code)
+	if !found {
+		t.Fatalf("6/%d has no %s", day, code)
+	}
+
+	return cdq.Regular.Close[0]
+}
+
+// existsIn 存储是否记录了某天
+func existsIn(t *testing.T, s Store, day int) bool {
+	t.Helper()
+
+	exists, err := s.Exists(market.America{}, testDate(t, 6, day))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
+
+func TestCompositeStore(t *testing.T) {
+
+	db := newTestFileSystem(t, FileSystemConfig{})
+	files := newTestFileSystem(t, FileSystemConfig{})
+	s := NewCompositeStore(db, files)
+
+	a := func(day int, close uint32) market.CompanyDailyQuote {
+		return market.CompanyDailyQuote{Company: market.Company{Code: "A"}, Regular: oneBar(testDate(t, 6, day), 100, close)}
+	}
+
+	// 6/1 只在旧存储中，6/2 新旧存储都有
+	saveDay(t, files, testDate(t, 6, 1), a(1, 110))
+	saveDay(t, files, testDate(t, 6, 2), a(2, 120))
+	saveDay(t, db, testDate(t, 6, 2), a(2, 121))
+
+	if !existsIn(t, s, 1) || closeOf(t, s, 1, "A") != 110 {
+		t.Error("a day only in the fallback store should be read from it")
+	}
+
+	if closeOf(t, s, 2, "A") != 121 {
+		t.Error("the primary store should take priority")
+	}
+
+	// 写入只写主存储
+	saveDay(t, s, testDate(t, 6, 3), a(3, 130))
+	if !existsIn(t, db, 3) || existsIn(t, files, 3) {
+		t.Error("Save should write only to the primary store")
+	}
+
+	if existsIn(t, s, 4) {
+		t.Error("6/4 was never recorded")
+	}
+
+	if _, err := s.Load(market.America{}, testDate(t, 6, 4)); err == nil {
+		t.Error("Load of a day in no store should fail")
+	}
+
+	// 从所有存储删除
+	if err := s.Delete(market.America{}, testDate(t, 6, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if existsIn(t, db, 2) || existsIn(t, files, 2) {
+		t.Error("Delete should remove the day from every store")
+	}
+}
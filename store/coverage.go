@@ -0,0 +1,44 @@
+package store
+
+import (
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// FirstGap 查找公司在[from, to)之间第一个缺失记录的交易日
+// 没有任何公司有正常交易时段报价的日期视为休市；未记录的周末不算缺失
+func FirstGap(s Store, _market market.Market, code string, from, to time.Time) (time.Time, bool, error) {
+
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+
+		exists, err := s.Exists(_market, date)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		if !exists {
+			if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+				continue
+			}
+
+			return date, true, nil
+		}
+
+		quote, err := s.Load(_market, date)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		// 休市
+		if quote.IsHoliday() {
+			continue
+		}
+
+		if _, found := quote.Find(code); !found {
+			return date, true, nil
+		}
+	}
+
+	return time.Time{}, false, nil
+}
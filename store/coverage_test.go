@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestFirstGap(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	a := market.Company{Code: "A"}
+	b := market.Company{Code: "B"}
+
+	// 6/1 有A；6/2 只有B的盘前报价，视为休市；6/3、6/4 周末未记录；6/5 有A；6/6 只有B；6/7 未记录
+	day := testDate(t, 6, 1)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 100, 100)})
+
+	day = testDate(t, 6, 2)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: b, Pre: oneBar(day, 100, 100)})
+
+	day = testDate(t, 6, 5)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 100, 100)})
+
+	day = testDate(t, 6, 6)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: b, Regular: oneBar(day, 100, 100)})
+
+	tests := []struct {
+		from, to time.Time
+		found    bool
+		gap      time.Time
+	}{
+		{testDate(t, 6, 1), testDate(t, 6, 6), false, time.Time{}},
+		{testDate(t, 6, 1), testDate(t, 6, 7), true, testDate(t, 6, 6)},
+		{testDate(t, 6, 7), testDate(t, 6, 8), true, testDate(t, 6, 7)},
+	}
+
+	for _, test := range tests {
+
+		gap, found, err := FirstGap(s, market.America{}, "A", test.from, test.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if found != test.found || !gap.Equal(test.gap) {
+			t.Errorf("FirstGap(%s, %s) = %s, %v, want %s, %v", test.from.Format("0102"), test.to.Format("0102"), gap, found, test.gap, test.found)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// CrossCheck 校验两个存储中市场某天的报价是否一致
+func CrossCheck(a, b Store, _market market.Market, date time.Time) error {
+
+	aq, err := a.Load(_market, date)
+	if err != nil {
+		return fmt.Errorf("[%s] 读取%s的报价时发生错误: %v", _market.Name(), date.Format("20060102"), err)
+	}
+
+	bq, err := b.Load(_market, date)
+	if err != nil {
+		return fmt.Errorf("[%s] 读取%s的报价时发生错误: %v", _market.Name(), date.Format("20060102"), err)
+	}
+
+	// 字节完全相同
+	if bytes.Equal(aq.Marshal(), bq.Marshal()) {
+		return nil
+	}
+
+	if len(aq.Quotes) != len(bq.Quotes) {
+		return fmt.Errorf("[%s] %s的上市公司数量不一致: %d %d", _market.Name(), date.Format("20060102"), len(aq.Quotes), len(bq.Quotes))
+	}
+
+	err = aq.Equal(bq)
+	if err != nil {
+		return fmt.Errorf("[%s] %s的报价不一致: %v", _market.Name(), date.Format("20060102"), err)
+	}
+
+	return nil
+}
+
+// Reconcile 比较两个存储中市场在[from, to)之间记录的上市公司，返回只在a中和只在b中的"代码@日期"
+// 某天只有一个存储有记录时，该天的所有上市公司都算作只在该存储中
+func Reconcile(a, b Store, _market market.Market, from, to time.Time) (onlyInA, onlyInB []string, err error) {
+
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+
+		aCodes, err := recordedCodes(a, _market, date)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bCodes, err := recordedCodes(b, _market, date)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, code := range aCodes.list {
+			if !bCodes.set[code] {
+				onlyInA = append(onlyInA, code+"@"+date.Format("20060102"))
+			}
+		}
+
+		for _, code := range bCodes.list {
+			if !aCodes.set[code] {
+				onlyInB = append(onlyInB, code+"@"+date.Format("20060102"))
+			}
+		}
+	}
+
+	return onlyInA, onlyInB, nil
+}
+
+// codeSet 按原有顺序保存的代码集合
+type codeSet struct {
+	list []string
+	set  map[string]bool
+}
+
+// recordedCodes 存储中市场某天记录的上市公司代码，没有记录时为空
+func recordedCodes(s Store, _market market.Market, date time.Time) (codeSet, error) {
+
+	codes := codeSet{set: make(map[string]bool)}
+
+	exists, err := s.Exists(_market, date)
+	if err != nil || !exists {
+		return codes, err
+	}
+
+	quote, err := s.Load(_market, date)
+	if err != nil {
+		return codes, fmt.Errorf("[%s] 读取%s的报价时发生错误: %v", _market.Name(), date.Format("20060102"), err)
+	}
+
+	for _, cdq := range quote.Quotes {
+		if !codes.set[cdq.Code] {
+			codes.list = append(codes.list, cdq.Code)
+			codes.set[cdq.Code] = true
+		}
+	}
+
+	return codes, nil
+}
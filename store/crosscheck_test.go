@@ -0,0 +1,96 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestCrossCheck(t *testing.T) {
+
+	day := testDate(t, 6, 1)
+	a := market.CompanyDailyQuote{Company: market.Company{Code: "A"}, Regular: oneBar(day, 100, 110)}
+	b := market.CompanyDailyQuote{Company: market.Company{Code: "B"}, Regular: oneBar(day, 200, 210)}
+	changed := market.CompanyDailyQuote{Company: market.Company{Code: "B"}, Regular: oneBar(day, 200, 211)}
+
+	tests := []struct {
+		name  string
+		left  []market.CompanyDailyQuote
+		right []market.CompanyDailyQuote
+		err   string // 为空时应一致
+	}{
+		{"identical", []market.CompanyDailyQuote{a, b}, []market.CompanyDailyQuote{a, b}, ""},
+		{"company count", []market.CompanyDailyQuote{a, b}, []market.CompanyDailyQuote{a}, "数量不一致"},
+		{"changed close", []market.CompanyDailyQuote{a, b}, []market.CompanyDailyQuote{a, changed}, "报价不一致"},
+	}
+
+	for _, test := range tests {
+
+		left := newTestFileSystem(t, FileSystemConfig{})
+		right := newTestFileSystem(t, FileSystemConfig{})
+		saveDay(t, left, day, test.left...)
+		saveDay(t, right, day, test.right...)
+
+		err := CrossCheck(left, right, market.America{}, day)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: CrossCheck = %v, want nil", test.name, err)
+		}
+
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: CrossCheck = %v, want an error containing %s", test.name, err, test.err)
+		}
+	}
+
+	// 一边没有记录
+	left := newTestFileSystem(t, FileSystemConfig{})
+	saveDay(t, left, day, a)
+	if err := CrossCheck(left, newTestFileSystem(t, FileSystemConfig{}), market.America{}, day); err == nil {
+		t.Error("CrossCheck against a store without the day should fail")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+
+	quote := func(day time.Time, code string) market.CompanyDailyQuote {
+		return market.CompanyDailyQuote{Company: market.Company{Code: code}, Regular: oneBar(day, 100, 110)}
+	}
+
+	files := newTestFileSystem(t, FileSystemConfig{})
+	db := newTestFileSystem(t, FileSystemConfig{})
+
+	// 6/1 一致；6/2 两边各少一家；6/5 只有files有记录；6/6 只有db有记录；6/7 两边都没有
+	day := testDate(t, 6, 1)
+	saveDay(t, files, day, quote(day, "A"), quote(day, "B"))
+	saveDay(t, db, day, quote(day, "B"), quote(day, "A"))
+
+	day = testDate(t, 6, 2)
+	saveDay(t, files, day, quote(day, "A"), quote(day, "B"))
+	saveDay(t, db, day, quote(day, "A"), quote(day, "C"))
+
+	day = testDate(t, 6, 5)
+	saveDay(t, files, day, quote(day, "A"), quote(day, "B"))
+
+	day = testDate(t, 6, 6)
+	saveDay(t, db, day, quote(day, "C"))
+
+	onlyInFiles, onlyInDB, err := Reconcile(files, db, market.America{}, testDate(t, 6, 1), testDate(t, 6, 8))
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if keys := strings.Join(onlyInFiles, ","); keys != "B@20170602,A@20170605,B@20170605" {
+		t.Errorf("only in files = %s, want B@20170602,A@20170605,B@20170605", keys)
+	}
+
+	if keys := strings.Join(onlyInDB, ","); keys != "C@20170602,C@20170606" {
+		t.Errorf("only in db = %s, want C@20170602,C@20170606", keys)
+	}
+
+	// 范围不含结束日期
+	onlyInFiles, onlyInDB, err = Reconcile(files, db, market.America{}, testDate(t, 6, 1), testDate(t, 6, 2))
+	if err != nil || len(onlyInFiles) != 0 || len(onlyInDB) != 0 {
+		t.Errorf("Reconcile of the matching day = %v, %v, %v, want nothing", onlyInFiles, onlyInDB, err)
+	}
+}
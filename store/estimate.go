@@ -0,0 +1,61 @@
+package store
+
+import (
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// StorageEstimate 存储空间估算
+type StorageEstimate struct {
+	Days          int     // 样本中有报价的天数
+	BytesPerDay   float64 // 平均每天的数据量(未压缩字节数)
+	PeriodsPerDay float64 // 平均每天的分时报价数
+	AnnualBytes   float64 // 按样本中交易日比例推算的每年数据量(未压缩字节数)
+}
+
+// EstimateStorage 根据[from, to)之间已记录的数据估算市场的存储空间
+func EstimateStorage(s Store, _market market.Market, from, to time.Time) (StorageEstimate, error) {
+
+	estimate := StorageEstimate{}
+
+	var totalBytes, totalPeriods, totalDays int
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+
+		exists, err := s.Exists(_market, date)
+		if err != nil {
+			return estimate, err
+		}
+
+		if !exists {
+			continue
+		}
+
+		quote, err := s.Load(_market, date)
+		if err != nil {
+			return estimate, err
+		}
+		totalDays++
+
+		// 休市
+		if quote.IsHoliday() {
+			continue
+		}
+
+		estimate.Days++
+		totalBytes += len(quote.Marshal())
+		for _, cdq := range quote.Quotes {
+			totalPeriods += int(cdq.Pre.Count + cdq.Regular.Count + cdq.Post.Count)
+		}
+	}
+
+	if estimate.Days == 0 {
+		return estimate, nil
+	}
+
+	estimate.BytesPerDay = float64(totalBytes) / float64(estimate.Days)
+	estimate.PeriodsPerDay = float64(totalPeriods) / float64(estimate.Days)
+	estimate.AnnualBytes = estimate.BytesPerDay * 365 * float64(estimate.Days) / float64(totalDays)
+
+	return estimate, nil
+}
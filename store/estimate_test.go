@@ -0,0 +1,85 @@
+package store
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestEstimateStorage(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	from, to := testDate(t, 6, 1), testDate(t, 6, 12)
+
+	// 6/1到6/8共记录8天，其中6/2休市、周末2天没有报价，6/9之后未记录
+	var totalBytes int
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+		if exists, _ := s.Exists(market.America{}, date); !exists {
+			continue
+		}
+
+		quote, err := s.Load(market.America{}, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !quote.IsHoliday() {
+			totalBytes += len(quote.Marshal())
+		}
+	}
+
+	estimate, err := EstimateStorage(s, market.America{}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if estimate.Days != 5 || estimate.PeriodsPerDay != 1 {
+		t.Errorf("Days = %d, PeriodsPerDay = %v, want 5 and 1", estimate.Days, estimate.PeriodsPerDay)
+	}
+
+	bytesPerDay := float64(totalBytes) / 5
+	if math.Abs(estimate.BytesPerDay-bytesPerDay) > 1e-9 {
+		t.Errorf("BytesPerDay = %v, want %v", estimate.BytesPerDay, bytesPerDay)
+	}
+
+	if annual := bytesPerDay * 365 * 5 / 8; math.Abs(estimate.AnnualBytes-annual) > 1e-6 {
+		t.Errorf("AnnualBytes = %v, want %v", estimate.AnnualBytes, annual)
+	}
+
+	// 没有记录时全为0
+	empty, err := EstimateStorage(s, market.America{}, testDate(t, 7, 1), testDate(t, 7, 8))
+	if err != nil || empty != (StorageEstimate{}) {
+		t.Errorf("EstimateStorage of an empty range = %+v, %v, want zero", empty, err)
+	}
+}
+
+func TestEstimateStorageHoliday(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+
+	// 休市日仍然保存了公司列表，只是分时报价都为空
+	a := market.Company{Code: "A"}
+	b := market.Company{Code: "B"}
+	day := testDate(t, 6, 1)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 9900, 10000)})
+	saveDay(t, s, testDate(t, 6, 2), market.CompanyDailyQuote{Company: a}, market.CompanyDailyQuote{Company: b})
+
+	quote, err := s.Load(market.America{}, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	estimate, err := EstimateStorage(s, market.America{}, day, testDate(t, 6, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bytesPerDay := float64(len(quote.Marshal()))
+	expected := StorageEstimate{Days: 1, BytesPerDay: bytesPerDay, PeriodsPerDay: 1, AnnualBytes: bytesPerDay * 365 / 2}
+	if estimate != expected {
+		t.Errorf("EstimateStorage = %+v, want %+v", estimate, expected)
+	}
+}
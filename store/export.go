@@ -0,0 +1,110 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// loadCompanyQuote 读取公司某天的报价
+func loadCompanyQuote(s Store, _market market.Market, code string, date time.Time) (*market.CompanyDailyQuote, error) {
+
+	quote, err := s.Load(_market, date)
+	if err != nil {
+		return nil, err
+	}
+
+	cdq, found := quote.Find(code)
+	if !found {
+		return nil, fmt.Errorf("[%s] %s没有%s的报价", _market.Name(), date.Format("20060102"), code)
+	}
+
+	return cdq, nil
+}
+
+// influxTagEscaper InfluxDB行协议中标签需要转义的字符
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// ExportInfluxLineProtocol 将公司某天的分时报价按InfluxDB行协议导出，每个报价一行，时间为纳秒
+func ExportInfluxLineProtocol(w io.Writer, s Store, _market market.Market, code string, date time.Time) error {
+
+	cdq, err := loadCompanyQuote(s, _market, code, date)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+	sessions := []struct {
+		name   string
+		series market.QuoteSeries
+	}{
+		{"pre", cdq.Pre},
+		{"regular", cdq.Regular},
+		{"post", cdq.Post},
+	}
+
+	for _, session := range sessions {
+		for index := 0; index < int(session.series.Count); index++ {
+
+			bar := session.series.Bar(index)
+			_, err = fmt.Fprintf(writer, "bar,market=%s,code=%s,session=%s open=%.2f,high=%.2f,low=%.2f,close=%.2f,volume=%di %d\n",
+				influxTagEscaper.Replace(_market.Name()),
+				influxTagEscaper.Replace(cdq.Code),
+				session.name,
+				float64(bar.Open)/100,
+				float64(bar.Max)/100,
+				float64(bar.Min)/100,
+				float64(bar.Close)/100,
+				bar.Volume,
+				int64(bar.Timestamp)*int64(time.Second),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Flush()
+}
+
+// ExportHighcharts 将公司某天的分时报价导出为Highcharts Stock使用的json，时间为毫秒
+// ohlc的每项为[时间, 开盘, 最高, 最低, 收盘]，volume的每项为[时间, 成交量]，按时间排列
+func ExportHighcharts(w io.Writer, s Store, _market market.Market, code string, date time.Time) error {
+
+	cdq, err := loadCompanyQuote(s, _market, code, date)
+	if err != nil {
+		return err
+	}
+
+	chart := struct {
+		OHLC   [][5]float64 `json:"ohlc"`
+		Volume [][2]float64 `json:"volume"`
+	}{
+		OHLC:   make([][5]float64, 0, cdq.Pre.Count+cdq.Regular.Count+cdq.Post.Count),
+		Volume: make([][2]float64, 0, cdq.Pre.Count+cdq.Regular.Count+cdq.Post.Count),
+	}
+
+	for _, series := range []market.QuoteSeries{cdq.Pre, cdq.Regular, cdq.Post} {
+		for index := 0; index < int(series.Count); index++ {
+
+			bar := series.Bar(index)
+			timestamp := float64(int64(bar.Timestamp) * 1000)
+
+			chart.OHLC = append(chart.OHLC, [5]float64{
+				timestamp,
+				float64(bar.Open) / 100,
+				float64(bar.Max) / 100,
+				float64(bar.Min) / 100,
+				float64(bar.Close) / 100,
+			})
+			chart.Volume = append(chart.Volume, [2]float64{timestamp, float64(bar.Volume)})
+		}
+	}
+
+	return json.NewEncoder(w).Encode(chart)
+}
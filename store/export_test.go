@@ -0,0 +1,98 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// exportDay 导出测试用的一天报价，盘前、盘中、盘后都有
+func exportDay(t *testing.T) (Store, time.Time) {
+	t.Helper()
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	date := testDate(t, 6, 1)
+
+	at := func(hour, minute int) uint32 {
+		return uint32(date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Unix())
+	}
+
+	saveDay(t, s, date, market.CompanyDailyQuote{
+		Company: market.Company{Code: "AAPL", Name: "Apple Inc."},
+		Pre: market.QuoteSeries{
+			Count: 1, Timestamp: []uint32{at(9, 0)},
+			Open: []uint32{15280}, Close: []uint32{15285}, Max: []uint32{15290}, Min: []uint32{15275}, Volume: []uint32{1200},
+		},
+		Regular: market.QuoteSeries{
+			Count: 2, Timestamp: []uint32{at(9, 30), at(9, 31)},
+			Open: []uint32{15317, 15290}, Close: []uint32{15291, 15256}, Max: []uint32{15320, 15295}, Min: []uint32{15280, 15250}, Volume: []uint32{901234, 252100},
+		},
+		Post: market.QuoteSeries{
+			Count: 1, Timestamp: []uint32{at(16, 0)},
+			Open: []uint32{15320}, Close: []uint32{15310}, Max: []uint32{15325}, Min: []uint32{15305}, Volume: []uint32{45000},
+		},
+	}, market.CompanyDailyQuote{
+		// 标签中的逗号、等号、空格需要转义
+		Company: market.Company{Code: "BRK,B =1", Name: "Berkshire Hathaway"},
+		Regular: oneBar(date, 21500, 21612),
+	})
+
+	return s, date
+}
+
+// checkGolden 比较输出与testdata中的期望文件
+func checkGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("output does not match testdata/%s\ngot:\n%s\nwant:\n%s", name, actual, expected)
+	}
+}
+
+func TestExportInfluxLineProtocol(t *testing.T) {
+
+	s, date := exportDay(t)
+
+	for _, code := range []string{"AAPL", "BRK,B =1"} {
+
+		var buffer bytes.Buffer
+		if err := ExportInfluxLineProtocol(&buffer, s, market.America{}, code, date); err != nil {
+			t.Fatalf("ExportInfluxLineProtocol(%s): %v", code, err)
+		}
+
+		name := "influx_aapl.golden"
+		if code != "AAPL" {
+			name = "influx_escaped.golden"
+		}
+		checkGolden(t, name, buffer.Bytes())
+	}
+
+	if err := ExportInfluxLineProtocol(ioutil.Discard, s, market.America{}, "MSFT", date); err == nil {
+		t.Error("exporting a company with no quotes should fail")
+	}
+}
+
+func TestExportHighcharts(t *testing.T) {
+
+	s, date := exportDay(t)
+
+	// 盘前、盘中、盘后按时间排列，时间为毫秒
+	var buffer bytes.Buffer
+	if err := ExportHighcharts(&buffer, s, market.America{}, "AAPL", date); err != nil {
+		t.Fatalf("ExportHighcharts: %v", err)
+	}
+	checkGolden(t, "highcharts_aapl.golden", buffer.Bytes())
+
+	if err := ExportHighcharts(ioutil.Discard, s, market.America{}, "MSFT", date); err == nil {
+		t.Error("exporting a company with no quotes should fail")
+	}
+}
@@ -1,33 +1,99 @@
 package store
 
 import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/nzai/go-utility/io"
 	"github.com/nzai/stockrecorder/market"
 )
 
+// SavePolicy 文件已存在时的保存策略
+type SavePolicy int
+
+const (
+	// SaveOverwrite 覆盖已存在的文件
+	SaveOverwrite SavePolicy = iota
+	// SaveSkip 跳过，保留已存在的文件
+	SaveSkip
+	// SaveBackup 将已存在的文件加上时间后缀备份后再保存
+	SaveBackup
+)
+
 // FileSystemConfig 文件系统配置
 type FileSystemConfig struct {
-	StoreRoot string // 存储根目录
+	StoreRoot  string      // 存储根目录
+	SavePolicy SavePolicy  // 文件已存在时的保存策略
+	FileMode   os.FileMode // 文件权限，新建的目录同时加上对应的执行权限，未设置时为0666并受umask影响
 }
 
 // FileSystem 文件系统存储服务
 type FileSystem struct {
 	config FileSystemConfig
+	root   *atomic.Value // 当前存储根目录，可以在运行中切换
 }
 
 // NewFileSystem 新建文件系统存储服务
 func NewFileSystem(config FileSystemConfig) *FileSystem {
-	return &FileSystem{config: config}
+
+	root := new(atomic.Value)
+	root.Store(config.StoreRoot)
+
+	return &FileSystem{config: config, root: root}
+}
+
+// storeRoot 当前存储根目录
+func (s FileSystem) storeRoot() string {
+
+	if s.root == nil {
+		return s.config.StoreRoot
+	}
+
+	return s.root.Load().(string)
+}
+
+// SwapStoreRoot 校验新目录可写后切换存储根目录，之后的读写使用新目录，已开始的读写不受影响
+func (s *FileSystem) SwapStoreRoot(root string) error {
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s不是目录", root)
+	}
+
+	// 确认可以写入
+	file, err := ioutil.TempFile(root, ".swap")
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	err = os.Remove(file.Name())
+	if err != nil {
+		return err
+	}
+
+	if s.root == nil {
+		s.root = new(atomic.Value)
+	}
+	s.root.Store(root)
+
+	return nil
 }
 
 // storePath 存储路径
 func (s FileSystem) storePath(_market market.Market, date time.Time) string {
 	return filepath.Join(
-		s.config.StoreRoot,
+		s.storeRoot(),
 		date.Format("2006"),
 		date.Format("01"),
 		date.Format("02"),
@@ -42,7 +108,113 @@ func (s FileSystem) Exists(_market market.Market, date time.Time) (bool, error)
 
 // Save 保存
 func (s FileSystem) Save(quote market.DailyQuote) error {
-	return io.WriteGzipBytes(s.storePath(quote.Market, quote.Date), quote.Marshal())
+
+	filePath := s.storePath(quote.Market, quote.Date)
+	if io.IsExists(filePath) {
+		switch s.config.SavePolicy {
+		case SaveSkip:
+			return nil
+		case SaveBackup:
+			err := os.Rename(filePath, filePath+"."+time.Now().Format("20060102150405"))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.writeGzip(filePath, quote.Marshal())
+}
+
+// Delete 删除
+func (s FileSystem) Delete(_market market.Market, date time.Time) error {
+
+	err := os.Remove(s.storePath(_market, date))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// fileMode 文件权限
+func (s FileSystem) fileMode() os.FileMode {
+
+	if s.config.FileMode == 0 {
+		return 0666
+	}
+
+	return s.config.FileMode.Perm()
+}
+
+// dirMode 目录权限，有读权限的同时加上执行权限
+func (s FileSystem) dirMode() os.FileMode {
+	mode := s.fileMode()
+	return mode | (mode&0444)>>2
+}
+
+// ensureDir 保证目录存在
+func (s FileSystem) ensureDir(dir string) error {
+
+	if io.IsExists(dir) {
+		return nil
+	}
+
+	//	递推
+	err := s.ensureDir(filepath.Dir(dir))
+	if err != nil {
+		return err
+	}
+
+	// 多个市场可能同时创建同一天的目录
+	err = os.Mkdir(dir, s.dirMode())
+	if err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// 配置了权限时不受umask影响
+	if s.config.FileMode != 0 {
+		return os.Chmod(dir, s.dirMode())
+	}
+
+	return nil
+}
+
+// writeGzip 压缩数据并写入文件
+func (s FileSystem) writeGzip(filePath string, data []byte) error {
+
+	err := s.ensureDir(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, s.fileMode())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if s.config.FileMode != 0 {
+		err = file.Chmod(s.fileMode())
+		if err != nil {
+			return err
+		}
+	}
+
+	// gzip 最高压缩
+	w, err := gzip.NewWriterLevel(file, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
 }
 
 // Load 读取
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nzai/go-utility/io"
+	"github.com/nzai/stockrecorder/market"
+)
+
+// companyDay 一家公司一天的报价，收盘价为close
+func companyDay(t *testing.T, code string, close uint32) market.DailyQuote {
+	t.Helper()
+
+	day := testDate(t, 6, 1)
+	_, offset := day.Zone()
+	cdq := market.CompanyDailyQuote{Company: market.Company{Code: code}, Regular: oneBar(day, close, close)}
+
+	return market.DailyQuote{Market: market.America{}, Date: day, UTCOffset: offset, Quotes: []market.CompanyDailyQuote{cdq}}
+}
+
+func TestSavePolicy(t *testing.T) {
+
+	tests := []struct {
+		policy  SavePolicy
+		close   uint32 // 再次保存后读出的收盘价
+		backups int    // 备份文件数
+	}{
+		{SaveOverwrite, 200, 0},
+		{SaveSkip, 100, 0},
+		{SaveBackup, 200, 1},
+	}
+
+	for _, test := range tests {
+
+		s := newTestFileSystem(t, FileSystemConfig{SavePolicy: test.policy})
+		first := companyDay(t, "A", 100)
+		second := companyDay(t, "A", 200)
+
+		if err := s.Save(first); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Save(second); err != nil {
+			t.Fatalf("policy %d: second Save: %v", test.policy, err)
+		}
+
+		quote, err := s.Load(market.America{}, first.Date)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if close := quote.Quotes[0].Regular.Close[0]; close != test.close {
+			t.Errorf("policy %d: close = %d, want %d", test.policy, close, test.close)
+		}
+
+		path := s.storePath(market.America{}, first.Date)
+		backups, err := filepath.Glob(path + ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(backups) != test.backups {
+			t.Errorf("policy %d: %d backups, want %d", test.policy, len(backups), test.backups)
+		}
+
+		// 备份的是第一次保存的内容
+		if len(backups) == 1 {
+			buffer, err := io.ReadAllGzipBytes(backups[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			old := market.DailyQuote{Market: market.America{}}
+			old.Unmarshal(buffer)
+			if old.Quotes[0].Regular.Close[0] != 100 {
+				t.Errorf("backup close = %d, want 100", old.Quotes[0].Regular.Close[0])
+			}
+		}
+	}
+}
+
+func TestFileMode(t *testing.T) {
+
+	tests := []struct {
+		mode    os.FileMode
+		file    os.FileMode
+		dir     os.FileMode
+		umasked bool // 未配置时受umask影响，只检查不超过默认权限
+	}{
+		{0, 0666, 0777, true},
+		{0640, 0640, 0750, false},
+		{0600, 0600, 0700, false},
+		// 只取权限位
+		{os.ModeSetuid | 0644, 0644, 0755, false},
+	}
+
+	for _, test := range tests {
+
+		s := newTestFileSystem(t, FileSystemConfig{FileMode: test.mode})
+		quote := companyDay(t, "A", 100)
+		if err := s.Save(quote); err != nil {
+			t.Fatal(err)
+		}
+
+		path := s.storePath(quote.Market, quote.Date)
+		file, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 年、月、日三级目录
+		dirs := []string{filepath.Dir(path), filepath.Dir(filepath.Dir(path)), filepath.Dir(filepath.Dir(filepath.Dir(path)))}
+		for _, dir := range dirs {
+
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.umasked && info.Mode().Perm()&^test.dir != 0 || !test.umasked && info.Mode().Perm() != test.dir {
+				t.Errorf("mode %o: dir %s is %o, want %o", test.mode, dir, info.Mode().Perm(), test.dir)
+			}
+		}
+
+		if test.umasked && file.Mode().Perm()&^test.file != 0 || !test.umasked && file.Mode().Perm() != test.file {
+			t.Errorf("mode %o: file is %o, want %o", test.mode, file.Mode().Perm(), test.file)
+		}
+	}
+}
+
+// tempDir 测试结束后删除的临时目录
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func TestSwapStoreRoot(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	roots := []string{s.storeRoot(), tempDir(t)}
+
+	// 切换目录的同时不断保存，每次保存都完整地落在其中一个目录
+	const writers, days = 4, 30
+	var wg sync.WaitGroup
+	for writer := 0; writer < writers; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+
+			for day := 0; day < days; day++ {
+				date := testDate(t, 1, 1).AddDate(0, 0, writer*days+day)
+				_, offset := date.Zone()
+				err := s.Save(market.DailyQuote{Market: market.America{}, Date: date, UTCOffset: offset, Quotes: []market.CompanyDailyQuote{
+					{Company: market.Company{Code: "A"}, Regular: oneBar(date, 100, 110)},
+				}})
+				if err != nil {
+					t.Errorf("Save %s: %v", date.Format("20060102"), err)
+				}
+			}
+		}(writer)
+	}
+
+	for index := 0; index < 20; index++ {
+		if err := s.SwapStoreRoot(roots[index%2]); err != nil {
+			t.Fatalf("SwapStoreRoot: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, root := range roots {
+
+		view := NewFileSystem(FileSystemConfig{StoreRoot: root})
+		for index := 0; index < writers*days; index++ {
+
+			date := testDate(t, 1, 1).AddDate(0, 0, index)
+			exists, _ := view.Exists(market.America{}, date)
+			if !exists {
+				continue
+			}
+			saved++
+
+			if _, err := view.Load(market.America{}, date); err != nil {
+				t.Errorf("Load %s from %s: %v", date.Format("20060102"), root, err)
+			}
+		}
+	}
+
+	if saved != writers*days {
+		t.Errorf("found %d saved days across both roots, want %d", saved, writers*days)
+	}
+
+	// 切换后的保存只写入新目录
+	if err := s.SwapStoreRoot(roots[1]); err != nil {
+		t.Fatal(err)
+	}
+	companyQuote := companyDay(t, "B", 200)
+	companyQuote.Date = testDate(t, 12, 1)
+	if err := s.Save(companyQuote); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, _ := NewFileSystem(FileSystemConfig{StoreRoot: roots[0]}).Exists(market.America{}, companyQuote.Date); exists {
+		t.Error("a save after the swap was written to the old root")
+	}
+
+	if exists, _ := s.Exists(market.America{}, companyQuote.Date); !exists {
+		t.Error("a save after the swap is missing from the new root")
+	}
+}
+
+func TestSwapStoreRootInvalid(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	root := s.storeRoot()
+
+	file := filepath.Join(tempDir(t), "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []string{filepath.Join(root, "missing"), file} {
+		if err := s.SwapStoreRoot(invalid); err == nil {
+			t.Errorf("SwapStoreRoot(%s) should fail", invalid)
+		}
+	}
+
+	// 校验失败时不切换
+	if s.storeRoot() != root {
+		t.Errorf("store root = %s after failed swaps, want %s", s.storeRoot(), root)
+	}
+}
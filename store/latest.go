@@ -0,0 +1,37 @@
+package store
+
+import (
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// LatestBar 从date(含)起向前最多查找days天，返回公司最近的一个分时报价
+func LatestBar(s Store, _market market.Market, code string, date time.Time, days int) (market.Bar, bool, error) {
+
+	for count := 0; count < days; count++ {
+
+		exists, err := s.Exists(_market, date)
+		if err != nil {
+			return market.Bar{}, false, err
+		}
+
+		if exists {
+			quote, err := s.Load(_market, date)
+			if err != nil {
+				return market.Bar{}, false, err
+			}
+
+			if cdq, found := quote.Find(code); found {
+				if bar, found := cdq.Last(); found {
+					return bar, true, nil
+				}
+			}
+		}
+
+		// 前移一天
+		date = date.AddDate(0, 0, -1)
+	}
+
+	return market.Bar{}, false, nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestLatestBar(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	tests := []struct {
+		code  string
+		date  time.Time
+		days  int
+		found bool
+		close uint32
+	}{
+		// 6/9、6/10 未记录，向前找到6/8
+		{"A", testDate(t, 6, 10), 3, true, 13310},
+		{"A", testDate(t, 6, 10), 2, false, 0},
+		// 6/6 只有B
+		{"A", testDate(t, 6, 6), 1, false, 0},
+		{"A", testDate(t, 6, 6), 2, true, 11000},
+		// 只有盘前报价时取盘前的最后一个
+		{"A", testDate(t, 6, 2), 1, true, 10100},
+		{"B", testDate(t, 6, 8), 5, true, 5000},
+		{"C", testDate(t, 6, 8), 10, false, 0},
+	}
+
+	for _, test := range tests {
+
+		bar, found, err := LatestBar(s, market.America{}, test.code, test.date, test.days)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if found != test.found || bar.Close != test.close {
+			t.Errorf("LatestBar(%s, %s, %d) = %d, %v, want %d, %v", test.code, test.date.Format("0102"), test.days, bar.Close, found, test.close, test.found)
+		}
+	}
+}
@@ -266,3 +266,28 @@ func (s Redis) loadQuoteSerie(_market market.Market, date time.Time, code, typeN
 
 	return qs, nil
 }
+
+// Delete 删除
+func (s Redis) Delete(_market market.Market, date time.Time) error {
+
+	prefix := fmt.Sprintf("%s:%s", strings.ToLower(_market.Name()), date.Format("20060102"))
+
+	// key:america:20160101:company value:[a aa aapl fb ibm ...]
+	companyKey := prefix + ":company"
+	companyCodes, err := s.client.SMembers(companyKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{prefix + ":offset", companyKey}
+	for _, code := range companyCodes {
+		keys = append(keys,
+			fmt.Sprintf("%s:%s:name", prefix, code),
+			fmt.Sprintf("%s:%s:pre", prefix, code),
+			fmt.Sprintf("%s:%s:regular", prefix, code),
+			fmt.Sprintf("%s:%s:post", prefix, code),
+		)
+	}
+
+	return s.client.Del(keys...).Err()
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// ApplyRetention 删除市场从from(含)起整天早于now-retention的记录，返回删除的天数，可重复执行
+func ApplyRetention(s Store, _market market.Market, from, now time.Time, retention time.Duration) (int, error) {
+
+	cutoff := now.Add(-retention)
+
+	deleted := 0
+	for date := from; !date.AddDate(0, 0, 1).After(cutoff); date = date.AddDate(0, 0, 1) {
+
+		exists, err := s.Exists(_market, date)
+		if err != nil {
+			return deleted, err
+		}
+
+		if !exists {
+			continue
+		}
+
+		err = s.Delete(_market, date)
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
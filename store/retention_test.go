@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestApplyRetention(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	// 保留5天，6/4零点之前结束的6/1、6/2、6/3删除
+	from, now := testDate(t, 5, 25), testDate(t, 6, 9)
+	deleted, err := ApplyRetention(s, market.America{}, from, now, 5*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+
+	if deleted != 3 {
+		t.Errorf("deleted %d days, want 3", deleted)
+	}
+
+	for day := 1; day <= 8; day++ {
+
+		exists, err := s.Exists(market.America{}, testDate(t, 6, day))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists != (day >= 4) {
+			t.Errorf("6/%d exists = %v after retention, want %v", day, exists, day >= 4)
+		}
+	}
+
+	// 重复执行不再删除
+	deleted, err = ApplyRetention(s, market.America{}, from, now, 5*24*time.Hour)
+	if err != nil || deleted != 0 {
+		t.Errorf("second ApplyRetention = %d, %v, want 0, nil", deleted, err)
+	}
+}
+
+func TestDeleteMissing(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	if err := s.Delete(market.America{}, testDate(t, 6, 1)); err != nil {
+		t.Errorf("deleting a day that was never saved = %v, want nil", err)
+	}
+}
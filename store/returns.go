@@ -0,0 +1,76 @@
+package store
+
+import (
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// Return 收益率
+type Return struct {
+	Date    time.Time // 日期
+	Percent float64   // 相对前一交易日收盘价的涨跌幅(%)
+}
+
+// DailyReturns 计算公司在[from, to)之间每个交易日收盘价相对前一交易日收盘价的涨跌幅
+// 休市日(当天没有任何公司有正常交易时段报价)跳过，缺失记录或当天没有该公司报价时中断，不会跨缺口计算
+func DailyReturns(s Store, _market market.Market, code string, from, to time.Time) ([]Return, error) {
+	return regularReturns(s, _market, code, from, to, func(series market.QuoteSeries) uint32 {
+		return series.Close[series.Count-1]
+	})
+}
+
+// OvernightReturns 计算公司在[from, to)之间每个交易日开盘价相对前一交易日收盘价的涨跌幅(隔夜跳空)
+// 与DailyReturns相同，休市日跳过，缺失记录时中断
+func OvernightReturns(s Store, _market market.Market, code string, from, to time.Time) ([]Return, error) {
+	return regularReturns(s, _market, code, from, to, func(series market.QuoteSeries) uint32 {
+		return series.Open[0]
+	})
+}
+
+// regularReturns 计算每个交易日正常交易时段的price相对前一交易日收盘价的涨跌幅
+func regularReturns(s Store, _market market.Market, code string, from, to time.Time, price func(market.QuoteSeries) uint32) ([]Return, error) {
+
+	var returns []Return
+	var previous uint32
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+
+		exists, err := s.Exists(_market, date)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			previous = 0
+			continue
+		}
+
+		quote, err := s.Load(_market, date)
+		if err != nil {
+			return nil, err
+		}
+
+		// 休市
+		if quote.IsHoliday() {
+			continue
+		}
+
+		cdq, found := quote.Find(code)
+		if !found || cdq.Regular.Count == 0 {
+			previous = 0
+			continue
+		}
+
+		current := price(cdq.Regular)
+		if previous > 0 {
+			returns = append(returns, Return{
+				Date:    date,
+				Percent: (float64(current) - float64(previous)) / float64(previous) * 100,
+			})
+		}
+
+		previous = cdq.Regular.Close[cdq.Regular.Count-1]
+	}
+
+	return returns, nil
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// newTestFileSystem 新建使用临时目录的文件系统存储
+func newTestFileSystem(t *testing.T, config FileSystemConfig) *FileSystem {
+	t.Helper()
+
+	root, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	config.StoreRoot = root
+	return NewFileSystem(config)
+}
+
+// testDate 美国市场所在时区的日期
+func testDate(t *testing.T, month time.Month, day int) time.Time {
+	t.Helper()
+
+	location, err := time.LoadLocation(market.America{}.Timezone())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return time.Date(2017, month, day, 0, 0, 0, 0, location)
+}
+
+// oneBar 只有一个报价的分时序列，价格单位为分
+func oneBar(date time.Time, open, close uint32) market.QuoteSeries {
+
+	timestamp := uint32(date.Add(10 * time.Hour).Unix())
+	return market.QuoteSeries{
+		Count:     1,
+		Timestamp: []uint32{timestamp},
+		Open:      []uint32{open},
+		Close:     []uint32{close},
+		Max:       []uint32{max32(open, close)},
+		Min:       []uint32{min32(open, close)},
+		Volume:    []uint32{100},
+	}
+}
+
+func max32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// saveDay 保存一天的报价
+func saveDay(t *testing.T, s Store, date time.Time, quotes ...market.CompanyDailyQuote) {
+	t.Helper()
+
+	_, offset := date.Zone()
+	err := s.Save(market.DailyQuote{Market: market.America{}, Date: date, UTCOffset: offset, Quotes: quotes})
+	if err != nil {
+		t.Fatalf("save %s: %v", date.Format("20060102"), err)
+	}
+}
+
+// saveReturnDays 保存收益率测试用的几天报价
+// 6/1 收盘100；6/2 只有盘前报价，视为休市；6/3、6/4 周末没有报价；6/5 开盘105收盘110；
+// 6/6 只有B的报价，A缺失；6/7 收盘121；6/8 开盘127.05收盘133.1
+func saveReturnDays(t *testing.T, s Store) {
+	t.Helper()
+
+	a := market.Company{Code: "A"}
+	b := market.Company{Code: "B"}
+
+	day := testDate(t, 6, 1)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 9900, 10000)})
+
+	day = testDate(t, 6, 2)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Pre: oneBar(day, 10000, 10100)})
+
+	saveDay(t, s, testDate(t, 6, 3))
+	saveDay(t, s, testDate(t, 6, 4))
+
+	day = testDate(t, 6, 5)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 10500, 11000)})
+
+	day = testDate(t, 6, 6)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: b, Regular: oneBar(day, 5000, 5000)})
+
+	day = testDate(t, 6, 7)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 12000, 12100)})
+
+	day = testDate(t, 6, 8)
+	saveDay(t, s, day, market.CompanyDailyQuote{Company: a, Regular: oneBar(day, 12705, 13310)})
+}
+
+// checkReturns 比较收益率
+func checkReturns(t *testing.T, name string, actual []Return, expected []Return) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Fatalf("%s = %+v, want %+v", name, actual, expected)
+	}
+
+	for index := range expected {
+		if !actual[index].Date.Equal(expected[index].Date) || math.Abs(actual[index].Percent-expected[index].Percent) > 1e-9 {
+			t.Errorf("%s[%d] = %+v, want %+v", name, index, actual[index], expected[index])
+		}
+	}
+}
+
+func TestDailyReturns(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	// 6/2只有盘前报价仍按休市跳过，6/6缺失A后中断，6/7不计算
+	returns, err := DailyReturns(s, market.America{}, "A", testDate(t, 6, 1), testDate(t, 6, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkReturns(t, "DailyReturns", returns, []Return{
+		{Date: testDate(t, 6, 5), Percent: 10},
+		{Date: testDate(t, 6, 8), Percent: 10},
+	})
+}
+
+func TestOvernightReturns(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	returns, err := OvernightReturns(s, market.America{}, "A", testDate(t, 6, 1), testDate(t, 6, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkReturns(t, "OvernightReturns", returns, []Return{
+		{Date: testDate(t, 6, 5), Percent: 5},
+		{Date: testDate(t, 6, 8), Percent: 5},
+	})
+}
+
+func TestOvernightGaps(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	a := market.Company{Code: "A"}
+
+	// 6/12至6/14连续三天：收盘100；高开2%收盘104；低开5%收盘99
+	for _, day := range []struct {
+		day         int
+		open, close uint32
+	}{
+		{12, 9900, 10000},
+		{13, 10200, 10400},
+		{14, 9880, 9900},
+	} {
+		date := testDate(t, 6, day.day)
+		saveDay(t, s, date, market.CompanyDailyQuote{Company: a, Regular: oneBar(date, day.open, day.close)})
+	}
+
+	returns, err := OvernightReturns(s, market.America{}, "A", testDate(t, 6, 12), testDate(t, 6, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkReturns(t, "OvernightReturns", returns, []Return{
+		{Date: testDate(t, 6, 13), Percent: 2},
+		{Date: testDate(t, 6, 14), Percent: -5},
+	})
+
+	// 6/15未记录，之后的6/16不与6/14比较
+	date := testDate(t, 6, 16)
+	saveDay(t, s, date, market.CompanyDailyQuote{Company: a, Regular: oneBar(date, 10000, 10100)})
+
+	returns, err = OvernightReturns(s, market.America{}, "A", testDate(t, 6, 12), testDate(t, 6, 17))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkReturns(t, "OvernightReturns across a missing day", returns, []Return{
+		{Date: testDate(t, 6, 13), Percent: 2},
+		{Date: testDate(t, 6, 14), Percent: -5},
+	})
+}
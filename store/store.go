@@ -14,4 +14,6 @@ type Store interface {
 	Save(quote market.DailyQuote) error
 	// 读取
 	Load(_market market.Market, date time.Time) (market.DailyQuote, error)
+	// 删除，不存在时不报错
+	Delete(_market market.Market, date time.Time) error
 }
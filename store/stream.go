@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// StreamBars 按时间顺序逐日读取公司在[from, to)之间的分时报价并通过通道发送，每次只加载一天
+// 发送完毕或ctx取消后关闭两个通道，错误通道最多返回一个错误
+func StreamBars(ctx context.Context, s Store, _market market.Market, code string, from, to time.Time) (<-chan market.Bar, <-chan error) {
+
+	bars := make(chan market.Bar)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(bars)
+		defer close(errs)
+
+		for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+
+			exists, err := s.Exists(_market, date)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if !exists {
+				continue
+			}
+
+			quote, err := s.Load(_market, date)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			cdq, found := quote.Find(code)
+			if !found {
+				continue
+			}
+
+			// 盘前、盘中、盘后依次发送
+			for _, series := range []market.QuoteSeries{cdq.Pre, cdq.Regular, cdq.Post} {
+				for index := 0; index < int(series.Count); index++ {
+					select {
+					case bars <- series.Bar(index):
+					case <-ctx.Done():
+						errs <- ctx.Err()
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return bars, errs
+}
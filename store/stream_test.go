@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+func TestStreamBars(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	// 6/3、6/4没有A，6/6缺失A，6/9未记录
+	bars, errs := StreamBars(context.Background(), s, market.America{}, "A", testDate(t, 6, 1), testDate(t, 6, 10))
+
+	var closes []uint32
+	var last uint32
+	for bar := range bars {
+		if bar.Timestamp <= last {
+			t.Errorf("bar at %d delivered after %d", bar.Timestamp, last)
+		}
+		last = bar.Timestamp
+		closes = append(closes, bar.Close)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatalf("StreamBars: %v", err)
+	}
+
+	expected := []uint32{10000, 10100, 11000, 12100, 13310}
+	if len(closes) != len(expected) {
+		t.Fatalf("closes = %v, want %v", closes, expected)
+	}
+
+	for index := range expected {
+		if closes[index] != expected[index] {
+			t.Errorf("close %d = %d, want %d", index, closes[index], expected[index])
+		}
+	}
+}
+
+func TestStreamBarsSessionOrder(t *testing.T) {
+
+	s, date := exportDay(t)
+
+	bars, errs := StreamBars(context.Background(), s, market.America{}, "AAPL", date, date.AddDate(0, 0, 1))
+
+	var volumes []uint32
+	for bar := range bars {
+		volumes = append(volumes, bar.Volume)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatalf("StreamBars: %v", err)
+	}
+
+	// 盘前、盘中、盘后依次发送
+	expected := []uint32{1200, 901234, 252100, 45000}
+	if len(volumes) != len(expected) {
+		t.Fatalf("volumes = %v, want %v", volumes, expected)
+	}
+
+	for index := range expected {
+		if volumes[index] != expected[index] {
+			t.Errorf("volume %d = %d, want %d", index, volumes[index], expected[index])
+		}
+	}
+}
+
+func TestStreamBarsCancel(t *testing.T) {
+
+	s := newTestFileSystem(t, FileSystemConfig{})
+	saveReturnDays(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bars, errs := StreamBars(ctx, s, market.America{}, "A", testDate(t, 6, 1), testDate(t, 6, 10))
+	if _, ok := <-bars; !ok {
+		t.Fatal("StreamBars closed before the first bar")
+	}
+
+	// 取消后不再发送，返回ctx的错误并关闭通道
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("error after cancel = %v, want %v", err, context.Canceled)
+	}
+
+	if bar, ok := <-bars; ok {
+		t.Errorf("received %+v after cancel, want the channel closed", bar)
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// TeeStore 同时写入多个存储，读取只读第一个存储，用于迁移期间新旧存储同时写入
+type TeeStore struct {
+	stores []Store
+}
+
+// NewTeeStore 新建同时写入的存储，primary为读取的存储
+func NewTeeStore(primary Store, others ...Store) *TeeStore {
+	return &TeeStore{stores: append([]Store{primary}, others...)}
+}
+
+// Exists 判断主存储是否记录过
+func (s TeeStore) Exists(_market market.Market, date time.Time) (bool, error) {
+	return s.stores[0].Exists(_market, date)
+}
+
+// Save 保存到所有存储，某个存储失败时仍然保存到其他存储
+func (s TeeStore) Save(quote market.DailyQuote) error {
+	return s.each(func(store Store) error {
+		return store.Save(quote)
+	})
+}
+
+// Load 从主存储读取
+func (s TeeStore) Load(_market market.Market, date time.Time) (market.DailyQuote, error) {
+	return s.stores[0].Load(_market, date)
+}
+
+// Delete 从所有存储删除
+func (s TeeStore) Delete(_market market.Market, date time.Time) error {
+	return s.each(func(store Store) error {
+		return store.Delete(_market, date)
+	})
+}
+
+// each 对每个存储执行操作，合并所有错误
+func (s TeeStore) each(action func(Store) error) error {
+
+	var messages []string
+	for index, store := range s.stores {
+		if err := action(store); err != nil {
+			messages = append(messages, fmt.Sprintf("[%d] %v", index, err))
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%d个存储失败: %s", len(messages), strings.Join(messages, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+)
+
+// brokenStore 保存和删除总是失败的存储
+type brokenStore struct {
+	Store
+}
+
+func (s brokenStore) Save(quote market.DailyQuote) error {
+	return errors.New("disk full")
+}
+
+func (s brokenStore) Delete(_market market.Market, date time.Time) error {
+	return errors.New("read-only")
+}
+
+func TestTeeStore(t *testing.T) {
+
+	previous := newTestFileSystem(t, FileSystemConfig{})
+	next := newTestFileSystem(t, FileSystemConfig{})
+	s := NewTeeStore(previous, next)
+
+	// 保存到所有存储，读取主存储
+	saveDay(t, s, testDate(t, 6, 1), market.CompanyDailyQuote{Company: market.Company{Code: "A"}, Regular: oneBar(testDate(t, 6, 1), 100, 110)})
+	if !existsIn(t, previous, 1) || !existsIn(t, next, 1) {
+		t.Error("Save should write to every store")
+	}
+
+	if !existsIn(t, s, 1) || closeOf(t, s, 1, "A") != 110 {
+		t.Error("Load should read the primary store")
+	}
+
+	if err := s.Delete(market.America{}, testDate(t, 6, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if existsIn(t, previous, 1) || existsIn(t, next, 1) {
+		t.Error("Delete should remove the day from every store")
+	}
+}
+
+func TestTeeStoreError(t *testing.T) {
+
+	healthy := newTestFileSystem(t, FileSystemConfig{})
+	s := NewTeeStore(brokenStore{newTestFileSystem(t, FileSystemConfig{})}, healthy)
+
+	// 一个存储失败时其他存储仍然保存，错误中包含失败的存储
+	day := testDate(t, 6, 1)
+	_, offset := day.Zone()
+	err := s.Save(market.DailyQuote{Market: market.America{}, Date: day, UTCOffset: offset})
+	if err == nil || !strings.Contains(err.Error(), "[0] disk full") || strings.Contains(err.Error(), "[1]") {
+		t.Errorf("Save = %v, want only the first store's error", err)
+	}
+
+	if !existsIn(t, healthy, 1) {
+		t.Error("the healthy store lost its write because another store failed")
+	}
+
+	err = s.Delete(market.America{}, day)
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("Delete = %v, want the first store's error", err)
+	}
+
+	if existsIn(t, healthy, 1) {
+		t.Error("the healthy store kept the day after Delete")
+	}
+}
@@ -0,0 +1,215 @@
+package udf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+	"github.com/nzai/stockrecorder/store"
+)
+
+// Handler 以TradingView UDF协议提供存储中的分时报价，商品代码格式为"市场:代码"，如"America:AAPL"
+type Handler struct {
+	store store.Store
+	mux   *http.ServeMux
+}
+
+// NewHandler 新建UDF处理器，提供/config、/symbols和/history
+func NewHandler(s store.Store) *Handler {
+
+	h := &Handler{store: s, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/config", h.config)
+	h.mux.HandleFunc("/symbols", h.symbols)
+	h.mux.HandleFunc("/history", h.history)
+
+	return h
+}
+
+// ServeHTTP 处理请求
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Config /config的返回
+type Config struct {
+	SupportedResolutions   []string `json:"supported_resolutions"`
+	SupportsGroupRequest   bool     `json:"supports_group_request"`
+	SupportsMarks          bool     `json:"supports_marks"`
+	SupportsSearch         bool     `json:"supports_search"`
+	SupportsTimescaleMarks bool     `json:"supports_timescale_marks"`
+	SupportsTime           bool     `json:"supports_time"`
+}
+
+// Symbol /symbols的返回
+type Symbol struct {
+	Name                 string   `json:"name"`
+	Ticker               string   `json:"ticker"`
+	Description          string   `json:"description"`
+	Type                 string   `json:"type"`
+	Session              string   `json:"session"`
+	Exchange             string   `json:"exchange"`
+	ListedExchange       string   `json:"listed_exchange"`
+	Timezone             string   `json:"timezone"`
+	MinMov               int      `json:"minmov"`
+	PriceScale           int      `json:"pricescale"`
+	HasIntraday          bool     `json:"has_intraday"`
+	HasDaily             bool     `json:"has_daily"`
+	IntradayMultipliers  []string `json:"intraday_multipliers"`
+	SupportedResolutions []string `json:"supported_resolutions"`
+	DataStatus           string   `json:"data_status"`
+}
+
+// History /history的返回，s为ok、no_data或error
+type History struct {
+	Status       string    `json:"s"`
+	ErrorMessage string    `json:"errmsg,omitempty"`
+	Time         []int64   `json:"t,omitempty"`
+	Open         []float64 `json:"o,omitempty"`
+	High         []float64 `json:"h,omitempty"`
+	Low          []float64 `json:"l,omitempty"`
+	Close        []float64 `json:"c,omitempty"`
+	Volume       []float64 `json:"v,omitempty"`
+}
+
+// 只记录了1分钟报价
+var resolutions = []string{"1"}
+
+const (
+	// maxHistoryDays /history一次最多查询的天数，每天都要访问一次存储
+	maxHistoryDays = 366
+)
+
+// config /config
+func (h *Handler) config(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Config{SupportedResolutions: resolutions})
+}
+
+// symbols /symbols?symbol=America:AAPL
+func (h *Handler) symbols(w http.ResponseWriter, r *http.Request) {
+
+	_market, code, err := parseSymbol(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, History{Status: "error", ErrorMessage: err.Error()})
+		return
+	}
+
+	ticker := _market.Name() + ":" + code
+	writeJSON(w, http.StatusOK, Symbol{
+		Name:                 code,
+		Ticker:               ticker,
+		Description:          ticker,
+		Type:                 "stock",
+		Session:              "24x7", // 包含盘前盘后
+		Exchange:             _market.Name(),
+		ListedExchange:       _market.Name(),
+		Timezone:             _market.Timezone(),
+		MinMov:               1,
+		PriceScale:           100, // 价格以分保存
+		HasIntraday:          true,
+		IntradayMultipliers:  resolutions,
+		SupportedResolutions: resolutions,
+		DataStatus:           "endofday",
+	})
+}
+
+// history /history?symbol=America:AAPL&resolution=1&from=1500000000&to=1500086400
+func (h *Handler) history(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	_market, code, err := parseSymbol(query.Get("symbol"))
+	if err != nil {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: err.Error()})
+		return
+	}
+
+	from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: "from格式不正确"})
+		return
+	}
+
+	to, err := strconv.ParseInt(query.Get("to"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: "to格式不正确"})
+		return
+	}
+
+	if from <= 0 || to <= from {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: "from和to的范围不正确"})
+		return
+	}
+
+	if to-from > maxHistoryDays*24*60*60 {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: fmt.Sprintf("一次最多查询%d天", maxHistoryDays)})
+		return
+	}
+
+	location, err := time.LoadLocation(_market.Timezone())
+	if err != nil {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: err.Error()})
+		return
+	}
+
+	// 存储按市场所在地的日期保存
+	start := time.Unix(from, 0).In(location)
+	end := time.Unix(to, 0).In(location)
+	startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+	endDate := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+
+	result := History{Status: "ok"}
+	bars, errs := store.StreamBars(r.Context(), h.store, _market, code, startDate, endDate)
+	for bar := range bars {
+
+		timestamp := int64(bar.Timestamp)
+		if timestamp < from || timestamp >= to {
+			continue
+		}
+
+		result.Time = append(result.Time, timestamp)
+		result.Open = append(result.Open, float64(bar.Open)/100)
+		result.High = append(result.High, float64(bar.Max)/100)
+		result.Low = append(result.Low, float64(bar.Min)/100)
+		result.Close = append(result.Close, float64(bar.Close)/100)
+		result.Volume = append(result.Volume, float64(bar.Volume))
+	}
+
+	if err = <-errs; err != nil {
+		writeJSON(w, http.StatusOK, History{Status: "error", ErrorMessage: err.Error()})
+		return
+	}
+
+	if len(result.Time) == 0 {
+		result = History{Status: "no_data"}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseSymbol 解析"市场:代码"格式的商品代码
+func parseSymbol(symbol string) (market.Market, string, error) {
+
+	parts := strings.SplitN(symbol, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, "", fmt.Errorf("商品代码%q格式不正确，应为市场:代码", symbol)
+	}
+
+	_market, err := market.Get(parts[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return _market, parts[1], nil
+}
+
+// writeJSON 输出json
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,162 @@
+package udf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nzai/stockrecorder/market"
+	"github.com/nzai/stockrecorder/store"
+)
+
+// newTestServer 保存2017-06-01 AAPL的盘前1个、盘中2个报价，返回提供UDF的测试服务器
+func newTestServer(t *testing.T) (*httptest.Server, time.Time) {
+	t.Helper()
+
+	root, err := ioutil.TempDir("", "udf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	location, err := time.LoadLocation(market.America{}.Timezone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2017, 6, 1, 0, 0, 0, 0, location)
+	at := func(hour, minute int) uint32 {
+		return uint32(date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Unix())
+	}
+
+	s := store.NewFileSystem(store.FileSystemConfig{StoreRoot: root})
+	_, offset := date.Zone()
+	err = s.Save(market.DailyQuote{Market: market.America{}, Date: date, UTCOffset: offset, Quotes: []market.CompanyDailyQuote{{
+		Company: market.Company{Code: "AAPL", Name: "Apple Inc."},
+		Pre: market.QuoteSeries{
+			Count: 1, Timestamp: []uint32{at(9, 0)},
+			Open: []uint32{15280}, Close: []uint32{15285}, Max: []uint32{15290}, Min: []uint32{15275}, Volume: []uint32{1200},
+		},
+		Regular: market.QuoteSeries{
+			Count: 2, Timestamp: []uint32{at(9, 30), at(9, 31)},
+			Open: []uint32{15317, 15290}, Close: []uint32{15291, 15256}, Max: []uint32{15320, 15295}, Min: []uint32{15280, 15250}, Volume: []uint32{901234, 252100},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NewHandler(s))
+	t.Cleanup(server.Close)
+
+	return server, date
+}
+
+// get 请求并解析json
+func get(t *testing.T, url string, value interface{}) int {
+	t.Helper()
+
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if err = json.NewDecoder(response.Body).Decode(value); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+
+	return response.StatusCode
+}
+
+func TestHistory(t *testing.T) {
+
+	server, date := newTestServer(t)
+
+	// 9:15至16:00，不含盘前9:00的报价
+	from, to := date.Add(9*time.Hour+15*time.Minute).Unix(), date.Add(16*time.Hour).Unix()
+
+	var history map[string]interface{}
+	get(t, fmt.Sprintf("%s/history?symbol=America:AAPL&resolution=1&from=%d&to=%d", server.URL, from, to), &history)
+
+	open := float64(date.Add(9*time.Hour + 30*time.Minute).Unix())
+	expected := map[string]interface{}{
+		"s": "ok",
+		"t": []interface{}{open, open + 60},
+		"o": []interface{}{153.17, 152.9},
+		"h": []interface{}{153.2, 152.95},
+		"l": []interface{}{152.8, 152.5},
+		"c": []interface{}{152.91, 152.56},
+		"v": []interface{}{901234.0, 252100.0},
+	}
+
+	if !reflect.DeepEqual(history, expected) {
+		t.Errorf("/history = %v, want %v", history, expected)
+	}
+}
+
+func TestHistoryStatus(t *testing.T) {
+
+	server, date := newTestServer(t)
+	day := date.Unix()
+
+	tests := []struct {
+		name   string
+		query  string
+		status string
+	}{
+		{"no bars in range", fmt.Sprintf("symbol=America:AAPL&from=%d&to=%d", day+86400, day+2*86400), "no_data"},
+		{"unknown company", fmt.Sprintf("symbol=America:MSFT&from=%d&to=%d", day, day+86400), "no_data"},
+		{"unknown market", fmt.Sprintf("symbol=Mars:AAPL&from=%d&to=%d", day, day+86400), "error"},
+		{"missing code", fmt.Sprintf("symbol=America:&from=%d&to=%d", day, day+86400), "error"},
+		{"bad from", fmt.Sprintf("symbol=America:AAPL&from=x&to=%d", day+86400), "error"},
+		{"from zero", fmt.Sprintf("symbol=America:AAPL&from=0&to=%d", day+86400), "error"},
+		{"to before from", fmt.Sprintf("symbol=America:AAPL&from=%d&to=%d", day+86400, day), "error"},
+		{"empty range", fmt.Sprintf("symbol=America:AAPL&from=%d&to=%d", day, day), "error"},
+		{"span too long", fmt.Sprintf("symbol=America:AAPL&from=%d&to=%d", day, day+(maxHistoryDays+1)*86400), "error"},
+		{"longest span", fmt.Sprintf("symbol=America:AAPL&from=%d&to=%d", day+86400, day+(maxHistoryDays+1)*86400), "no_data"},
+	}
+
+	for _, test := range tests {
+
+		var history History
+		status := get(t, server.URL+"/history?"+test.query, &history)
+		if status != http.StatusOK || history.Status != test.status || len(history.Time) != 0 {
+			t.Errorf("%s: /history = %d %+v, want 200 with s=%s and no bars", test.name, status, history, test.status)
+		}
+
+		if test.status == "error" && history.ErrorMessage == "" {
+			t.Errorf("%s: /history error has no errmsg", test.name)
+		}
+	}
+}
+
+func TestSymbolsAndConfig(t *testing.T) {
+
+	server, _ := newTestServer(t)
+
+	var symbol Symbol
+	if status := get(t, server.URL+"/symbols?symbol=america:AAPL", &symbol); status != http.StatusOK {
+		t.Fatalf("/symbols status = %d", status)
+	}
+
+	if symbol.Ticker != "America:AAPL" || symbol.Timezone != "America/New_York" || symbol.PriceScale != 100 || !symbol.HasIntraday {
+		t.Errorf("/symbols = %+v", symbol)
+	}
+
+	var failed History
+	if status := get(t, server.URL+"/symbols?symbol=AAPL", &failed); status != http.StatusNotFound || failed.Status != "error" {
+		t.Errorf("/symbols without a market = %d %+v, want 404 error", status, failed)
+	}
+
+	var config Config
+	get(t, server.URL+"/config", &config)
+	if !reflect.DeepEqual(config.SupportedResolutions, []string{"1"}) {
+		t.Errorf("/config resolutions = %v, want [1]", config.SupportedResolutions)
+	}
+}